@@ -0,0 +1,65 @@
+package main
+
+import (
+	"agent/bot"
+	"agent/bot/programs"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// 🛠️ Serve operator endpoints: POST /reload applies the config file again,
+// GET /bots reports each bot's state and relays and GET /bots/{name}/history
+// lists recent program runs, filtered by ?status= and capped by ?limit=
+func serveAdmin(addr string, manager *bot.BotManager, reload func() (bot.ReloadReport, error)) {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /reload", func(w http.ResponseWriter, r *http.Request) {
+		log.Println("🔄 Reload requested over admin API")
+
+		report, err := reload()
+		if err != nil {
+			log.Printf("❌ Reload failed: %v", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error":  err.Error(),
+				"report": report,
+			})
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	})
+
+	mux.HandleFunc("GET /bots", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, manager.Status())
+	})
+
+	mux.HandleFunc("GET /bots/{name}/history", func(w http.ResponseWriter, r *http.Request) {
+		limit := 0
+		if value := r.URL.Query().Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a non-negative number"})
+				return
+			}
+		}
+
+		history, found := manager.History(r.PathValue("name"), programs.Status(r.URL.Query().Get("status")), limit)
+		if !found {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "no such bot"})
+			return
+		}
+		writeJSON(w, http.StatusOK, history)
+	})
+
+	log.Printf("🛠️ Admin API listening on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("❌ Admin API stopped: %v", err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -2,89 +2,166 @@ package bot
 
 import (
 	"agent/bot/programs"
+	"agent/bot/signers"
 	"agent/core"
 	"context"
+	"fmt"
 	"log"
-	"sync"
+	"strings"
+	"sync/atomic"
+	"time"
 
-	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip19"
 )
 
+// programStopTimeout bounds how long a finished program may take to stop,
+// which holds up the bot's mailbox
+const programStopTimeout = 10 * time.Second
+
 // BaseBot implements the core bot functionalities
 type BaseBot struct {
-	mu sync.Mutex
-
 	Config   core.BotConfig
 	Programs []programs.BotProgram
 
-	Context    context.Context
+	Context    context.Context // Lives until the bot is stopped, used to publish
 	CancelFunc context.CancelFunc
 
-	RelayURL         string
-	SecretKey        string
-	PublicKey        string
-	IsActiveListener bool
+	ListenContext context.Context // Ends when the bot stops listening, before it stops publishing
+	StopListening context.CancelFunc
 
-	Relay *nostr.Relay
-
-	Listener  EventListener
-	Publisher Publisher
-	EventBus  *EventBus
+	PublicKey        string
+	IsActiveListener atomic.Bool
+
+	Pool    *RelayPool
+	Signer  signers.Signer
+	Cursors CursorStore
+	Dedup   *Deduplicator
+	States  StateStore // Program progress kept across restarts
+
+	Listener   EventListener
+	Publisher  Publisher
+	EventBus   *EventBus
+	Supervisor *Supervisor
+	Mailbox    *Mailbox // Runs programs one message at a time
+	History    *ExecutionHistory
+	Bridge     *Bridge // Forwards EventBus messages to other processes, nil when not configured
+
+	states map[programs.BotProgram]programs.State // State handles of stateful programs, owned by the mailbox
 }
 
 // NewBaseBot initializes a new instance of BaseBot
-func NewBaseBot(config core.BotConfig, listener EventListener, publisher Publisher, eventBus *EventBus) *BaseBot {
-	ctx, cancel := context.WithCancel(context.Background())
+func NewBaseBot(config core.BotConfig, listener EventListener, publisher Publisher, eventBus *EventBus) (*BaseBot, error) {
+	// 🔑 Keys stay with the signer, which may live in another process
+	signer, err := signers.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create signer for %s: %w", config.Name, err)
+	}
 
-	_, sk, _ := nip19.Decode(config.Nsec)
-	pk, _ := nostr.GetPublicKey(sk.(string))
+	pk, err := signer.GetPublicKey(context.Background())
+	if err != nil {
+		signer.Close()
+		return nil, fmt.Errorf("could not get public key for %s: %w", config.Name, err)
+	}
+
+	if config.Npub != "" {
+		if _, expected, err := nip19.Decode(config.Npub); err != nil || expected != pk {
+			signer.Close()
+			return nil, fmt.Errorf("signer for %s does not hold the key of %s", config.Name, config.Npub)
+		}
+	}
 
-	return &BaseBot{
-		Config:           config,
-		RelayURL:         config.RelayURL,
-		SecretKey:        sk.(string),
-		PublicKey:        pk,
-		Context:          ctx,
-		CancelFunc:       cancel,
-		IsActiveListener: false,
-		Listener:         listener,
-		Publisher:        publisher,
-		EventBus:         eventBus,
+	var cursors CursorStore = NewMemoryCursorStore()
+	if !config.Cursor.Disabled {
+		if cursors, err = NewFileCursorStore(config.Cursor.Directory(), config.Name); err != nil {
+			signer.Close()
+			return nil, err
+		}
+	}
+
+	dedup, err := NewDeduplicator(config.Dedup, config.Name)
+	if err != nil {
+		signer.Close()
+		return nil, fmt.Errorf("could not load seen events for %s: %w", config.Name, err)
 	}
+
+	state, err := NewStateStore(config.State, config.Name)
+	if err != nil {
+		signer.Close()
+		dedup.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	listenCtx, stopListening := context.WithCancel(ctx)
+
+	bot := &BaseBot{
+		Config:        config,
+		Pool:          NewRelayPool(config.Relays()),
+		Signer:        signer,
+		Cursors:       cursors,
+		Dedup:         dedup,
+		States:        state,
+		PublicKey:     pk,
+		Context:       ctx,
+		CancelFunc:    cancel,
+		ListenContext: listenCtx,
+		StopListening: stopListening,
+		Listener:      listener,
+		Publisher:     publisher,
+		EventBus:      eventBus,
+		Mailbox:       newMailbox(config.MailboxCapacity()),
+		History:       NewExecutionHistory(config.HistoryCapacity()),
+	}
+	bot.Supervisor = NewSupervisor(bot, NewBackoffPolicy(config.Reconnect))
+
+	if config.Bridge.Enabled() {
+		bot.Bridge = NewBridge(config.Name, config.Bridge, eventBus, NewBackoffPolicy(config.Reconnect))
+	}
+
+	go bot.Mailbox.run(ctx)
+
+	return bot, nil
 }
 
-// Starts the bot
+// Starts the bot and keeps it connected until it is stopped
 func (b *BaseBot) Start() {
-	err := b.connectToRelay()
-	if err != nil {
-		log.Fatalf("❌ Failed to connect: %v", err)
+	if b.Bridge != nil {
+		if err := b.Bridge.Start(); err != nil {
+			log.Printf("❌ [%s] %v", b.Config.Name, err)
+		}
 	}
-	b.Listener.StartListening(b)
+	b.Supervisor.Run(b.ListenContext)
 }
 
-// Connects to the relay
-func (b *BaseBot) connectToRelay() error {
-	relay, err := nostr.RelayConnect(b.Context, b.RelayURL)
-	if err != nil {
+// Connects to the relay pool
+func (b *BaseBot) connectToRelays() error {
+	if err := b.Pool.Connect(b.Context); err != nil {
 		return err
 	}
 
-	b.Relay = relay
+	relays := strings.Join(b.Pool.Connected(), ", ")
 	// 📝 Check if there are aliases before logging them
 	if len(b.Config.Aliases) > 0 {
-		log.Printf("📡 [%s] %v connected to [%s] ✅ ", b.Config.Name, b.Config.Aliases, b.RelayURL)
+		log.Printf("📡 [%s] %v connected to [%s] ✅ ", b.Config.Name, b.Config.Aliases, relays)
 	} else {
-		log.Printf("📡 [%s] connected to [%s] ✅ ", b.Config.Name, b.RelayURL)
+		log.Printf("📡 [%s] connected to [%s] ✅ ", b.Config.Name, relays)
 	}
 	return nil
 }
 
-// Stops the bot gracefully
+// Stops the bot and releases its relays, bridge, signer and seen events. See
+// BotManager.StopAll to let in-flight work finish first
 func (b *BaseBot) Stop() {
+	b.StopListening()
 	b.CancelFunc()
-	if b.Relay != nil {
-		b.Relay.Close()
+	if b.Bridge != nil {
+		b.Bridge.Close()
+	}
+	b.Pool.Close()
+	b.Signer.Close()
+	b.Dedup.Close()
+	if err := b.States.Close(); err != nil {
+		log.Printf("⚠️ [%s] Could not close program state: %v", b.Config.Name, err)
 	}
 	log.Println("🛑 Bot stopped gracefully")
 }
@@ -104,11 +181,13 @@ func (b *BaseBot) GetPublicKey() string {
 	return b.PublicKey
 }
 
-// GetNextReceiver picks the next peer for a given program
-func (bot *BaseBot) GetNextReceiver(program *programs.ChatterProgram) string {
-	// bot.mu.Lock()
-	// defer bot.mu.Unlock()
+func (b *BaseBot) GetChannelID() string {
+	return b.Config.ChannelID
+}
 
+// GetNextReceiver picks the next peer for a given program. Programs call it
+// from the mailbox, which owns their state
+func (bot *BaseBot) GetNextReceiver(program *programs.ChatterProgram) string {
 	if len(program.Peers) == 0 {
 		log.Println("⚠️ No peers available.")
 		return ""
@@ -123,61 +202,149 @@ func (b *BaseBot) Publish(message *core.BusMessage) {
 	b.Publisher.Broadcast(b, message)
 }
 
+// After runs fn once the delay has passed, outside the mailbox, so programs
+// can reply or start long jobs without holding up the next message. fn must
+// not touch program state, and is skipped if the bot stops first
+func (b *BaseBot) After(delay time.Duration, fn func()) {
+	b.Mailbox.pending.add(1)
+	go func() {
+		defer b.Mailbox.pending.add(-1)
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			fn()
+		case <-b.Context.Done():
+		}
+	}()
+}
+
 func (b *BaseBot) IsReady() bool {
-	return b.IsActiveListener
+	return b.IsActiveListener.Load()
 }
 
-func (bot *BaseBot) AssignPrograms(p []programs.BotProgram) {
-	bot.mu.Lock()
-	defer bot.mu.Unlock()
+// State returns where the bot is in its connection lifecycle
+func (b *BaseBot) State() BotState {
+	return b.Supervisor.State()
+}
 
-	// ✅ Expand slice `p` into individual elements
-	bot.Programs = append(bot.Programs, p...)
+func (bot *BaseBot) AssignPrograms(p []programs.BotProgram) {
+	bot.Mailbox.Call(bot.Context, func() { bot.assignProgramsLocked(p) })
 
 	log.Printf("🧮 [%s] received [%d] programs ✅", bot.Config.Name, len(p))
 }
 
 func (bot *BaseBot) RemoveProgram(p programs.BotProgram) {
-	bot.mu.Lock()
-	defer bot.mu.Unlock()
+	bot.Mailbox.Call(bot.Context, func() { bot.removeProgramLocked(p) })
+}
+
+// assignProgramsLocked adds programs from a mailbox item
+func (bot *BaseBot) assignProgramsLocked(p []programs.BotProgram) {
+	// ✅ Expand slice `p` into individual elements
+	bot.Programs = append(bot.Programs, p...)
+}
 
+// removeProgramLocked removes a program from a mailbox item, stopping it so
+// its jobs, connections and goroutines don't outlive it
+func (bot *BaseBot) removeProgramLocked(p programs.BotProgram) {
 	for i, program := range bot.Programs {
 		if program == p {
 			bot.Programs = append(bot.Programs[:i], bot.Programs[i+1:]...)
+			delete(bot.states, p)
 			log.Printf("🗑️ [%s] Removed completed program: %T", bot.Config.Name, p)
 			break
 		}
 	}
+
+	if stopper, ok := p.(programs.Stopper); ok {
+		ctx, cancel := context.WithTimeout(bot.Context, programStopTimeout)
+		defer cancel()
+		if err := stopper.Stop(ctx); err != nil {
+			log.Printf("⚠️ [%s] Could not stop [%s]: %v", bot.Config.Name, programs.Name(p), err)
+		}
+	}
+}
+
+// UpdatePeers hands a new peer list to the programs that address other bots
+func (bot *BaseBot) UpdatePeers(peers []string) {
+	bot.Mailbox.Call(bot.Context, func() {
+		for _, program := range bot.Programs {
+			if aware, ok := program.(programs.PeerAware); ok {
+				aware.SetPeers(peers)
+			}
+		}
+	})
 }
 
 func (bot *BaseBot) ResetPrograms() {
-	bot.Programs = []programs.BotProgram{}
+	bot.Mailbox.Call(bot.Context, func() {
+		bot.Programs = []programs.BotProgram{}
+		bot.states = nil
+	})
+}
+
+// ProgramState returns the handle a program loads and saves its state through
+func (bot *BaseBot) ProgramState(program string) programs.State {
+	return &programState{store: bot.States, key: StateKey{Bot: bot.Config.Name, Program: program}}
+}
+
+// trackStates remembers the state handles of stateful programs so their
+// state is saved after every run
+func (bot *BaseBot) trackStates(states map[programs.BotProgram]programs.State) {
+	bot.Mailbox.Call(bot.Context, func() {
+		if bot.states == nil {
+			bot.states = make(map[programs.BotProgram]programs.State)
+		}
+		for program, state := range states {
+			bot.states[program] = state
+		}
+	})
 }
 
-// ExecutePrograms runs all active programs for a bot
+// ExecutePrograms queues the message for the bot's programs and returns at
+// once. Messages are dropped when the mailbox is full
 func (bot *BaseBot) ExecutePrograms(message *core.BusMessage) {
-	bot.mu.Lock()
-	defer bot.mu.Unlock()
+	if !bot.Mailbox.Post(func() { bot.runPrograms(message) }) {
+		log.Printf("📪 [%s] Mailbox full, dropping message (%d dropped so far)", bot.Config.Name, bot.Mailbox.Dropped())
+	}
+}
 
+// runPrograms runs all active programs for a bot on its mailbox
+func (bot *BaseBot) runPrograms(message *core.BusMessage) {
 	log.Printf("⚙️ [%s] Executing [%d] Programs on message: %s", bot.Config.Name, len(bot.Programs), message.Payload)
 
-	var programsToRemove []int
+	var programsToRemove []programs.BotProgram
 
-	for i, program := range bot.Programs {
+	for _, program := range bot.Programs {
 		if program.ShouldRun(message) {
-			result := program.Run(bot, message)
-			log.Printf("[%s] [%s]", bot.Config.Name, result)
+			started := time.Now()
+			result := program.Run(bot.Context, bot, message)
+			result.Program = programs.Name(program)
+			result.Duration = time.Since(started)
+
+			bot.History.Record(Execution{At: started, EventID: message.EventID, Result: result})
+			if result.Status == programs.StatusError {
+				log.Printf("❌ [%s] [%s] failed: %v", bot.Config.Name, result.Program, result.Err)
+			} else {
+				log.Printf("[%s] [%s] %s", bot.Config.Name, result.Program, result)
+			}
+
+			if stateful, ok := program.(programs.Stateful); ok && bot.states[program] != nil {
+				if err := stateful.SaveState(bot.states[program]); err != nil {
+					log.Printf("⚠️ [%s] Could not save state of [%s]: %v", bot.Config.Name, result.Program, err)
+				}
+			}
 
 			if !program.IsActive() {
-				programsToRemove = append(programsToRemove, i)
+				programsToRemove = append(programsToRemove, program)
 			}
 		}
 	}
 
 	// ✅ Now remove all completed programs
-	for i := len(programsToRemove) - 1; i >= 0; i-- {
-		index := programsToRemove[i]
-		log.Printf("🗑️ [%s] Removing completed program: %T", bot.Config.Name, bot.Programs[index])
-		bot.Programs = append(bot.Programs[:index], bot.Programs[index+1:]...)
+	for _, program := range programsToRemove {
+		bot.removeProgramLocked(program)
 	}
 }
@@ -2,13 +2,14 @@ package bot
 
 import (
 	"agent/core"
+	"context"
 
 	"github.com/nbd-wtf/go-nostr"
 )
 
 // EventListener defines behavior for listening to events
 type EventListener interface {
-	StartListening(bot *BaseBot)                   // Starts listening for events
+	StartListening(bot *BaseBot) error             // Listens for events until the subscription ends
 	ProcessEvent(bot *BaseBot, event *nostr.Event) // Processes a received event
 	HandleConnectionLoss(bot *BaseBot)             // Handles relay disconnections
 }
@@ -22,3 +23,9 @@ type EventHandler interface {
 type Publisher interface {
 	Broadcast(bot *BaseBot, message *core.BusMessage) error // Publishes a message
 }
+
+// Flusher is implemented by publishers that buffer messages and must send
+// them before the bot shuts down
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
@@ -2,12 +2,19 @@ package bot
 
 import (
 	"agent/bot/programs"
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"sync"
 )
 
 type BotManager struct {
 	Bots     []*BaseBot
 	Programs map[*BaseBot][]programs.BotProgram
+
+	mu      sync.Mutex // Serializes starting, reloading and stopping
+	stopped bool       // StopAll ran, reloads must not start bots again
 }
 
 func NewBotManager() *BotManager {
@@ -22,64 +29,165 @@ func (m *BotManager) AddBot(bot *BaseBot) {
 }
 
 func (m *BotManager) StartAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.AssignPrograms()
 
 	for _, bot := range m.Bots {
+		bot.Supervisor.starting()
 		go bot.Start()
 	}
 }
 
+// StopAll shuts every bot down in order: listeners stop first so no new work
+// arrives, in-flight EventBus handlers, mailboxes and delayed replies get until
+// the context deadline to finish, then programs and publishers are flushed and
+// relays closed
+func (m *BotManager) StopAll(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	log.Printf("🛑 Stopping [%d] bots...", len(m.Bots))
+	m.stopped = true
+	return m.stop(ctx, m.Bots)
+}
+
+// stop runs the StopAll sequence on the given bots. Callers hold m.mu
+func (m *BotManager) stop(ctx context.Context, bots []*BaseBot) error {
+	var errs []error
+
+	// 👂 Stop listening, which emits each bot's BotStoppedEvent
+	for _, bot := range bots {
+		bot.StopListening()
+	}
+	for _, bot := range bots {
+		if err := bot.Supervisor.Wait(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", bot.Config.Name, err))
+		}
+	}
+
+	// 🚎 Let handlers finish queueing work
+	drained := make(map[*EventBus]bool)
+	for _, bot := range bots {
+		if drained[bot.EventBus] {
+			continue
+		}
+		drained[bot.EventBus] = true
+		if err := bot.EventBus.Drain(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", bot.Config.Name, err))
+		}
+	}
+
+	// 📬 Let queued program runs and delayed replies or jobs finish
+	for _, bot := range bots {
+		if err := bot.Mailbox.Drain(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", bot.Config.Name, err))
+		}
+	}
+
+	// 🧮 Release program resources such as hub websockets and crawler connections
+	for _, bot := range bots {
+		errs = append(errs, m.stopPrograms(ctx, bot)...)
+	}
+
+	// 📤 Flush buffered publishes while the relays are still open
+	for _, bot := range bots {
+		if flusher, ok := bot.Publisher.(Flusher); ok {
+			if err := flusher.Flush(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", bot.Config.Name, err))
+			}
+		}
+	}
+
+	for _, bot := range bots {
+		bot.Stop()
+		bot.EventBus.Close()
+	}
+
+	return errors.Join(errs...)
+}
+
+// InitializePrograms builds the bot's programs from its config, initializes
+// them against the bot's context and attaches them, stopping any it had before
 func (m *BotManager) InitializePrograms(bot *BaseBot) {
 	buffer := []programs.BotProgram{}
 
 	bot.ResetPrograms()
+	m.stopPrograms(bot.Context, bot)
 
-	var allPeers []string
-	for _, b := range m.Bots {
-		allPeers = append(allPeers, b.PublicKey)
-	}
+	peers := m.peersOf(bot)
+	states := make(map[programs.BotProgram]programs.State)
+	seen := make(map[string]int)
 
-	if bot.Config.Name == "Yin" {
-		log.Printf("🔌 Attaching [ChatterProgram] to [%s] ✅", bot.Config.Name)
-		buffer = append(buffer, &programs.ChatterProgram{
-			ProgramConfig: bot.Config.ProgramConfig,
-			Leader:        true,
-			Peers:         filterPeers(allPeers, bot.PublicKey),
-		})
-	} else if bot.Config.Name == "Yang" {
-		log.Printf("🔌 Attaching [ResponderProgram] to [%s] ✅", bot.Config.Name)
-		buffer = append(buffer, &programs.ResponderProgram{
-			ProgramConfig: bot.Config.ProgramConfig,
-			Peers:         filterPeers(allPeers, bot.PublicKey),
-		})
-	} else if bot.Config.Name == "HypeWizard" {
-		log.Printf("🔌 Attaching [ConductorProgram] to [%s] ✅", bot.Config.Name)
+	for _, programConfig := range bot.Config.ProgramList() {
+		// State is keyed by type, numbered from the second program of a type
+		name := programConfig.Type
+		if seen[programConfig.Type]++; seen[programConfig.Type] > 1 {
+			name = fmt.Sprintf("%s#%d", programConfig.Type, seen[programConfig.Type])
+		}
 
-		conductor := &programs.ConductorProgram{
-			ProgramConfig: bot.Config.ProgramConfig,
-			Peers:         filterPeers(allPeers, bot.PublicKey),
+		program, err := programs.New(programConfig, peers)
+		if err != nil {
+			log.Printf("❌ [%s] Could not attach program: %v", bot.Config.Name, err)
+			continue
 		}
 
-		conductor.InitCrawlerClient(bot.Config.ProgramConfig.WorkerConfig.Address)
-		buffer = append(buffer, conductor)
-	} else if bot.Config.Name == "Telegram" {
-		log.Printf("🔌 Attaching [CallbackProgram] to [%s] ✅", bot.Config.Name)
-		buffer = append(buffer, &programs.CallbackProgram{
-			ProgramConfig: bot.Config.ProgramConfig,
-			Peers:         filterPeers(allPeers, bot.PublicKey),
-		})
+		// 💾 Pick up where the program was before a restart
+		if stateful, ok := program.(programs.Stateful); ok {
+			state := bot.ProgramState(name)
+			if err := stateful.LoadState(state); err != nil {
+				log.Printf("⚠️ [%s] Could not restore state of [%s], starting fresh: %v", bot.Config.Name, name, err)
+			}
+			states[program] = state
+		}
+
+		if initializer, ok := program.(programs.Initializer); ok {
+			if err := initializer.Init(bot.Context, bot); err != nil {
+				log.Printf("❌ [%s] Could not initialize [%s]: %v", bot.Config.Name, programConfig.Type, err)
+				delete(states, program)
+				continue
+			}
+		}
+
+		log.Printf("🔌 Attaching [%s] to [%s] ✅", programConfig.Type, bot.Config.Name)
+		buffer = append(buffer, program)
 	}
 
+	bot.trackStates(states)
 	bot.AssignPrograms(buffer)
 	m.Programs[bot] = buffer
 }
 
+// stopPrograms calls Stop on the bot's programs that hold resources and forgets them
+func (m *BotManager) stopPrograms(ctx context.Context, bot *BaseBot) []error {
+	var errs []error
+	for _, program := range m.Programs[bot] {
+		if stopper, ok := program.(programs.Stopper); ok {
+			if err := stopper.Stop(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", bot.Config.Name, programs.Name(program), err))
+			}
+		}
+	}
+	delete(m.Programs, bot)
+	return errs
+}
+
 func (m *BotManager) AssignPrograms() {
 	for _, bot := range m.Bots {
 		m.InitializePrograms(bot)
 	}
 }
 
+// **peersOf** lists the public keys of every other managed bot
+func (m *BotManager) peersOf(bot *BaseBot) []string {
+	var allPeers []string
+	for _, b := range m.Bots {
+		allPeers = append(allPeers, b.PublicKey)
+	}
+	return filterPeers(allPeers, bot.PublicKey)
+}
+
 // **filterPeers** removes the bot's own public key from the peer list
 func filterPeers(peers []string, exclude string) []string {
 	var filtered []string
@@ -90,3 +198,64 @@ func filterPeers(peers []string, exclude string) []string {
 	}
 	return filtered
 }
+
+// BotStatus is a snapshot of a managed bot
+type BotStatus struct {
+	Name      string        `json:"name"`
+	PublicKey string        `json:"public_key"`
+	State     BotState      `json:"state"`
+	Relays    []RelayHealth `json:"relays"`
+	EventBus  BusStats      `json:"event_bus"`
+	Dropped   int64         `json:"mailbox_dropped"`        // Messages the mailbox had no room for
+	Bridged   []string      `json:"bridge_peers,omitempty"` // Node IDs of the processes the EventBus is bridged to
+
+	ProgramRuns  map[programs.Status]uint64 `json:"program_runs"`           // Program runs by status since the bot started
+	Dependencies []programs.Health          `json:"dependencies,omitempty"` // Services the bot's programs rely on, e.g. the crawler
+}
+
+// Status reports the state and relays of every managed bot
+func (m *BotManager) Status() []BotStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := make([]BotStatus, 0, len(m.Bots))
+	for _, bot := range m.Bots {
+		var bridged []string
+		if bot.Bridge != nil {
+			bridged = bot.Bridge.Peers()
+		}
+		var dependencies []programs.Health
+		for _, program := range m.Programs[bot] {
+			if reporter, ok := program.(programs.HealthReporter); ok {
+				dependencies = append(dependencies, reporter.Health()...)
+			}
+		}
+		status = append(status, BotStatus{
+			Name:      bot.Config.Name,
+			PublicKey: bot.PublicKey,
+			State:     bot.State(),
+			Relays:    bot.Pool.Health(),
+			EventBus:  bot.EventBus.Stats(),
+			Dropped:   bot.Mailbox.Dropped(),
+			Bridged:   bridged,
+
+			ProgramRuns:  bot.History.Counts(),
+			Dependencies: dependencies,
+		})
+	}
+	return status
+}
+
+// History returns up to limit recent program runs of the named bot, newest
+// first, keeping only the given status unless it is empty
+func (m *BotManager) History(name string, status programs.Status, limit int) ([]Execution, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, bot := range m.Bots {
+		if bot.Config.Name == name {
+			return bot.History.Recent(status, limit), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"agent/bot/programs"
+	"agent/core"
+	"context"
+	"testing"
+)
+
+// finiteProgram goes inactive after maxRuns, like a program that reached
+// its max_run_count, and records being stopped
+type finiteProgram struct {
+	maxRuns int
+	runs    int
+	stops   int
+}
+
+func (p *finiteProgram) ShouldRun(message *core.BusMessage) bool { return true }
+func (p *finiteProgram) IsActive() bool                          { return p.runs < p.maxRuns }
+
+func (p *finiteProgram) Run(ctx context.Context, bot programs.Bot, message *core.BusMessage) programs.Result {
+	p.runs++
+	return programs.OK()
+}
+
+func (p *finiteProgram) Stop(ctx context.Context) error {
+	p.stops++
+	return nil
+}
+
+func (p *finiteProgram) LoadState(state programs.State) error { return nil }
+
+func (p *finiteProgram) SaveState(state programs.State) error { return nil }
+
+// discardState accepts saves and has nothing to load
+type discardState struct{}
+
+func (discardState) Load(out interface{}) (bool, error) { return false, nil }
+func (discardState) Save(value interface{}) error       { return nil }
+
+func TestFinishedProgramIsStoppedAndForgotten(t *testing.T) {
+	bot := newTestBot(t, 10)
+
+	finite, other := &finiteProgram{maxRuns: 2}, &countingProgram{}
+	bot.trackStates(map[programs.BotProgram]programs.State{finite: discardState{}})
+	bot.AssignPrograms([]programs.BotProgram{finite, other})
+
+	for i := 0; i < 4; i++ {
+		bot.ExecutePrograms(&core.BusMessage{})
+	}
+	drain(t, bot.Mailbox)
+
+	var (
+		remaining   []programs.BotProgram
+		tracked     bool
+		runs, stops int
+		otherRuns   int
+	)
+	bot.Mailbox.Call(context.Background(), func() {
+		remaining = append(remaining, bot.Programs...)
+		_, tracked = bot.states[finite]
+		runs, stops, otherRuns = finite.runs, finite.stops, other.runs
+	})
+
+	if runs != 2 || stops != 1 {
+		t.Fatalf("finished program ran %d times and stopped %d times, want 2 and 1", runs, stops)
+	}
+	if len(remaining) != 1 || remaining[0] != other || otherRuns != 4 {
+		t.Fatalf("programs = %v after %d runs, want only the other program after 4", remaining, otherRuns)
+	}
+	if tracked {
+		t.Fatal("state of the finished program is still tracked")
+	}
+}
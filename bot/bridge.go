@@ -0,0 +1,411 @@
+package bot
+
+import (
+	"agent/core"
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// BridgeProtocolVersion is sent in every frame. Peers speaking another
+// version are disconnected during the handshake
+const BridgeProtocolVersion = 1
+
+const (
+	maxBridgeFrame  = 1 << 20          // Largest frame a peer may send, in bytes
+	bridgeSendQueue = 256              // Frames buffered per peer before new ones are dropped
+	bridgeHandshake = 10 * time.Second // Time a peer gets to say hello and prove the secret
+)
+
+// bridgeFrame is one length-prefixed JSON message on a bridge connection
+type bridgeFrame struct {
+	Version int              `json:"v"`
+	Type    string           `json:"type"`            // "hello", "auth" or "message"
+	Node    string           `json:"node,omitempty"`  // Sender's node ID, in hello frames
+	Nonce   string           `json:"nonce,omitempty"` // Challenge the peer signs with the shared secret, in hello frames
+	Proof   string           `json:"proof,omitempty"` // HMAC of the peer's nonce, in auth frames
+	Event   core.EventType   `json:"event,omitempty"`
+	Message *core.BusMessage `json:"message,omitempty"`
+}
+
+// Bridge forwards selected EventBus messages to and from other agent
+// processes over Unix or TCP sockets. Messages that came in over a bridge
+// are never sent back out, so peers must be connected directly. With a
+// shared secret, peers prove they know it before any message is accepted
+type Bridge struct {
+	name   string
+	config core.BridgeConfig
+	bus    *EventBus
+	policy BackoffPolicy
+	node   string // Random ID that tags the messages this bridge publishes
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu            sync.Mutex
+	listener      net.Listener
+	peers         map[*bridgePeer]struct{}
+	subscriptions []*Subscription
+}
+
+// bridgePeer is one connected process
+type bridgePeer struct {
+	conn net.Conn
+	node string
+	send chan bridgeFrame
+}
+
+// NewBridge creates the bridge of a bot's EventBus. Start connects it
+func NewBridge(name string, config core.BridgeConfig, bus *EventBus, policy BackoffPolicy) *Bridge {
+	ctx, cancel := context.WithCancel(context.Background())
+	id := make([]byte, 6)
+	rand.Read(id)
+
+	return &Bridge{
+		name:   name,
+		config: config,
+		bus:    bus,
+		policy: policy,
+		node:   name + "-" + hex.EncodeToString(id),
+		ctx:    ctx,
+		cancel: cancel,
+		peers:  make(map[*bridgePeer]struct{}),
+	}
+}
+
+// Start listens for peers, dials the configured ones and begins forwarding
+// outbound routes. Dialed peers are redialed with backoff until Close
+func (b *Bridge) Start() error {
+	for _, route := range b.config.Routes {
+		if route.Direction == "in" {
+			continue
+		}
+		subscription := b.bus.SubscribeEvents(core.EventType(route.Event), b.forward)
+		b.mu.Lock()
+		b.subscriptions = append(b.subscriptions, subscription)
+		b.mu.Unlock()
+	}
+
+	if b.config.Listen != "" {
+		network, addr, err := core.ParseBridgeAddress(b.config.Listen)
+		if err != nil {
+			return err
+		}
+		if network == "unix" {
+			os.Remove(addr) // Left behind by a process that did not shut down cleanly
+		}
+		if core.ExposedBridgeAddress(b.config.Listen) && b.config.Secret == "" {
+			return fmt.Errorf("bridge needs a secret to listen on %s", b.config.Listen)
+		}
+
+		listener, err := net.Listen(network, addr)
+		if err != nil {
+			return fmt.Errorf("bridge could not listen on %s: %w", b.config.Listen, err)
+		}
+		b.mu.Lock()
+		b.listener = listener
+		b.mu.Unlock()
+
+		log.Printf("🌉 [%s] Bridge listening on %s", b.name, b.config.Listen)
+		b.wg.Add(1)
+		go b.accept(listener)
+	}
+
+	for _, address := range b.config.Connect {
+		b.wg.Add(1)
+		go b.dial(address)
+	}
+	return nil
+}
+
+// ServeConn runs the bridge protocol on an established connection until it
+// fails or the bridge is closed
+func (b *Bridge) ServeConn(conn net.Conn) error {
+	defer conn.Close()
+
+	// Close the connection when the bridge closes, unblocking the reader
+	stop := context.AfterFunc(b.ctx, func() { conn.Close() })
+	defer stop()
+
+	reader := bufio.NewReader(conn)
+	node, err := b.handshake(conn, reader)
+	if err != nil {
+		return fmt.Errorf("bridge handshake failed: %w", err)
+	}
+
+	peer := &bridgePeer{conn: conn, node: node, send: make(chan bridgeFrame, bridgeSendQueue)}
+	b.mu.Lock()
+	b.peers[peer] = struct{}{}
+	b.mu.Unlock()
+	log.Printf("🌉 [%s] Bridged to %s", b.name, peer.node)
+
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		for frame := range peer.send {
+			if err := writeBridgeFrame(conn, frame); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.peers, peer)
+		b.mu.Unlock()
+		close(peer.send)
+		<-writerDone
+		log.Printf("🌉 [%s] Bridge to %s closed", b.name, peer.node)
+	}()
+
+	for {
+		frame, err := readBridgeFrame(reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) || b.ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if frame.Type != "message" || frame.Message == nil {
+			continue
+		}
+		if !b.routes(frame.Event, "in") {
+			continue // Not something this side wants
+		}
+
+		frame.Message.Bridged = peer.node
+		b.bus.Publish(frame.Event, frame.Message)
+	}
+}
+
+// handshake exchanges hellos and, with a secret, proofs of it. It returns the
+// peer's node ID
+func (b *Bridge) handshake(conn net.Conn, reader *bufio.Reader) (string, error) {
+	conn.SetDeadline(time.Now().Add(bridgeHandshake))
+	defer conn.SetDeadline(time.Time{})
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	if err := writeBridgeFrame(conn, bridgeFrame{Version: BridgeProtocolVersion, Type: "hello", Node: b.node, Nonce: hex.EncodeToString(nonce)}); err != nil {
+		return "", err
+	}
+	hello, err := readBridgeFrame(reader)
+	if err != nil {
+		return "", err
+	}
+	if hello.Type != "hello" || hello.Node == "" {
+		return "", fmt.Errorf("expected hello, got %q", hello.Type)
+	}
+	if b.config.Secret == "" {
+		return hello.Node, nil
+	}
+
+	if err := writeBridgeFrame(conn, bridgeFrame{Version: BridgeProtocolVersion, Type: "auth", Proof: b.prove(hello.Nonce)}); err != nil {
+		return "", err
+	}
+	auth, err := readBridgeFrame(reader)
+	if err != nil {
+		return "", err
+	}
+	if auth.Type != "auth" || !hmac.Equal([]byte(auth.Proof), []byte(b.prove(hex.EncodeToString(nonce)))) {
+		return "", fmt.Errorf("%s does not know the bridge secret", hello.Node)
+	}
+	return hello.Node, nil
+}
+
+// prove signs a peer's nonce with the shared secret
+func (b *Bridge) prove(nonce string) string {
+	mac := hmac.New(sha256.New, []byte(b.config.Secret))
+	mac.Write([]byte(nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publishes reports whether peer messages of the event type may reach the
+// bot's publisher. Routes opt in with publish, so a peer cannot have the bot
+// sign and send arbitrary events
+func (b *Bridge) Publishes(eventType core.EventType) bool {
+	for _, route := range b.config.Routes {
+		if route.Publish && route.Direction != "out" && matchEventType(core.EventType(route.Event), eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Close disconnects every peer, stops listening and unsubscribes from the bus
+func (b *Bridge) Close() {
+	b.cancel()
+
+	b.mu.Lock()
+	if b.listener != nil {
+		b.listener.Close()
+	}
+	for _, subscription := range b.subscriptions {
+		subscription.Unsubscribe()
+	}
+	b.subscriptions = nil
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+// Peers lists the node IDs of the connected processes
+func (b *Bridge) Peers() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	peers := make([]string, 0, len(b.peers))
+	for peer := range b.peers {
+		peers = append(peers, peer.node)
+	}
+	return peers
+}
+
+// forward sends a local message to every peer
+func (b *Bridge) forward(eventType core.EventType, message *core.BusMessage) {
+	if message.Bridged != "" {
+		return // Came from a peer, sending it on could loop
+	}
+
+	frame := bridgeFrame{Version: BridgeProtocolVersion, Type: "message", Event: eventType, Message: message}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for peer := range b.peers {
+		select {
+		case peer.send <- frame:
+		default:
+			log.Printf("📪 [%s] Bridge to %s is full, dropped [%s] message", b.name, peer.node, eventType)
+		}
+	}
+}
+
+// routes reports whether a route forwards the event type in the direction
+func (b *Bridge) routes(eventType core.EventType, direction string) bool {
+	for _, route := range b.config.Routes {
+		if route.Direction != "" && route.Direction != "both" && route.Direction != direction {
+			continue
+		}
+		if matchEventType(core.EventType(route.Event), eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bridge) accept(listener net.Listener) {
+	defer b.wg.Done()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if b.ctx.Err() == nil {
+				log.Printf("❌ [%s] Bridge stopped accepting: %v", b.name, err)
+			}
+			return
+		}
+
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			if err := b.ServeConn(conn); err != nil {
+				log.Printf("⚠️ [%s] Bridge peer %s: %v", b.name, conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// dial keeps a connection to the peer at address open until Close
+func (b *Bridge) dial(address string) {
+	defer b.wg.Done()
+
+	network, addr, err := core.ParseBridgeAddress(address)
+	if err != nil {
+		log.Printf("❌ [%s] %v", b.name, err)
+		return
+	}
+
+	var dialer net.Dialer
+	for attempt := 1; ; attempt++ {
+		conn, err := dialer.DialContext(b.ctx, network, addr)
+		if err == nil {
+			attempt = 0
+			if err := b.ServeConn(conn); err != nil {
+				log.Printf("⚠️ [%s] Bridge to %s: %v", b.name, address, err)
+			}
+		} else if b.ctx.Err() == nil {
+			log.Printf("⚠️ [%s] Could not reach bridge %s: %v", b.name, address, err)
+		}
+
+		if b.ctx.Err() != nil {
+			return
+		}
+
+		timer := time.NewTimer(b.policy.Delay(max(attempt, 1)))
+		select {
+		case <-timer.C:
+		case <-b.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// writeBridgeFrame writes a 4-byte big-endian length followed by the JSON frame
+func writeBridgeFrame(w io.Writer, frame bridgeFrame) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxBridgeFrame {
+		return fmt.Errorf("bridge frame of %d bytes exceeds %d", len(data), maxBridgeFrame)
+	}
+
+	buffer := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buffer, uint32(len(data)))
+	copy(buffer[4:], data)
+	_, err = w.Write(buffer)
+	return err
+}
+
+// readBridgeFrame reads one frame, rejecting oversized frames and other protocol versions
+func readBridgeFrame(r io.Reader) (bridgeFrame, error) {
+	var frame bridgeFrame
+
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return frame, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxBridgeFrame {
+		return frame, fmt.Errorf("bridge frame of %d bytes exceeds %d", size, maxBridgeFrame)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return frame, err
+	}
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return frame, fmt.Errorf("invalid bridge frame: %w", err)
+	}
+	if frame.Version != BridgeProtocolVersion {
+		return frame, fmt.Errorf("bridge protocol version %d is not supported (want %d)", frame.Version, BridgeProtocolVersion)
+	}
+	return frame, nil
+}
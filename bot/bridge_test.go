@@ -0,0 +1,243 @@
+package bot
+
+import (
+	"agent/core"
+	"bufio"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// connPair returns the two ends of a loopback socket. Unlike net.Pipe its
+// writes are buffered, so both sides can send their hello at once
+func connPair(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := listener.Accept()
+		accepted <- conn
+	}()
+
+	dialed, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := <-accepted
+	if served == nil {
+		t.Fatal("accept failed")
+	}
+	return dialed, served
+}
+
+func newTestBus(t *testing.T) *EventBus {
+	t.Helper()
+
+	bus := NewEventBus(NewBusOptions(core.EventBusConfig{}))
+	t.Cleanup(bus.Close)
+	return bus
+}
+
+// newTestBridge starts a bridge on bus without listening or dialing
+func newTestBridge(t *testing.T, name string, bus *EventBus, config core.BridgeConfig) *Bridge {
+	t.Helper()
+
+	bridge := NewBridge(name, config, bus, NewBackoffPolicy(core.ReconnectConfig{}))
+	if err := bridge.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(bridge.Close)
+	return bridge
+}
+
+// connect serves both ends of a socket pair and waits until each side sees the other
+func connect(t *testing.T, a, b *Bridge) {
+	t.Helper()
+
+	left, right := connPair(t)
+	go a.ServeConn(left)
+	go b.ServeConn(right)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(a.Peers()) == 0 || len(b.Peers()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("bridges did not connect")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// collect subscribes to an event type and passes received messages on
+func collect(bus *EventBus, eventType core.EventType) chan *core.BusMessage {
+	received := make(chan *core.BusMessage, 10)
+	bus.Subscribe(eventType, func(message *core.BusMessage) { received <- message })
+	return received
+}
+
+func expectMessage(t *testing.T, received chan *core.BusMessage) *core.BusMessage {
+	t.Helper()
+
+	select {
+	case message := <-received:
+		return message
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message arrived")
+		return nil
+	}
+}
+
+func expectNothing(t *testing.T, received chan *core.BusMessage) {
+	t.Helper()
+
+	select {
+	case message := <-received:
+		t.Fatalf("unexpected message %+v", message)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestBridgeRoutesByDirection(t *testing.T) {
+	busA, busB := newTestBus(t), newTestBus(t)
+	a := newTestBridge(t, "a", busA, core.BridgeConfig{Routes: []core.BridgeRoute{
+		{Event: "dm_*", Direction: "out"},
+		{Event: "group_response", Direction: "in"},
+	}})
+	b := newTestBridge(t, "b", busB, core.BridgeConfig{Routes: []core.BridgeRoute{
+		{Event: "dm_*", Direction: "in"},
+		{Event: "group_response", Direction: "in"},
+	}})
+	connect(t, a, b)
+
+	dmsAtB := collect(busB, "dm_response")
+	groupAtA := collect(busA, "group_response")
+	otherAtB := collect(busB, "other")
+
+	busA.Publish("dm_response", &core.BusMessage{Payload: core.ContentStructure{Text: "hi"}})
+	message := expectMessage(t, dmsAtB)
+	if message.Payload.Text != "hi" || !strings.HasPrefix(message.Bridged, "a-") {
+		t.Fatalf("got %+v, want text hi bridged from a", message)
+	}
+
+	// b only takes group_response in, so it never sends it to a
+	busB.Publish("group_response", &core.BusMessage{})
+	expectNothing(t, groupAtA)
+
+	busA.Publish("other", &core.BusMessage{})
+	expectNothing(t, otherAtB)
+}
+
+func TestBridgeDoesNotEchoBridgedMessages(t *testing.T) {
+	busA, busB := newTestBus(t), newTestBus(t)
+	routes := []core.BridgeRoute{{Event: "dm_response"}}
+	a := newTestBridge(t, "a", busA, core.BridgeConfig{Routes: routes})
+	b := newTestBridge(t, "b", busB, core.BridgeConfig{Routes: routes})
+	connect(t, a, b)
+
+	atA := collect(busA, "dm_response")
+	atB := collect(busB, "dm_response")
+
+	busA.Publish("dm_response", &core.BusMessage{})
+	if message := expectMessage(t, atA); message.Bridged != "" {
+		t.Fatalf("local message marked bridged from %s", message.Bridged)
+	}
+	expectMessage(t, atB)
+
+	// b must not send the message back to a
+	expectNothing(t, atA)
+}
+
+func TestBridgeSecret(t *testing.T) {
+	routes := []core.BridgeRoute{{Event: "dm_response"}}
+
+	busA, busB := newTestBus(t), newTestBus(t)
+	a := newTestBridge(t, "a", busA, core.BridgeConfig{Routes: routes, Secret: "s3cret"})
+	b := newTestBridge(t, "b", busB, core.BridgeConfig{Routes: routes, Secret: "s3cret"})
+	connect(t, a, b)
+
+	atB := collect(busB, "dm_response")
+	busA.Publish("dm_response", &core.BusMessage{})
+	expectMessage(t, atB)
+
+	c := newTestBridge(t, "c", newTestBus(t), core.BridgeConfig{Routes: routes, Secret: "wrong"})
+	left, right := connPair(t)
+	errs := make(chan error, 2)
+	go func() { errs <- a.ServeConn(left) }()
+	go func() { errs <- c.ServeConn(right) }()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err == nil || !strings.Contains(err.Error(), "secret") {
+			t.Fatalf("handshake with the wrong secret: %v", err)
+		}
+	}
+}
+
+func TestBridgeRefusesExposedListenWithoutSecret(t *testing.T) {
+	bridge := NewBridge("a", core.BridgeConfig{Listen: "tcp://0.0.0.0:0", Routes: []core.BridgeRoute{{Event: "*"}}}, newTestBus(t), BackoffPolicy{})
+	defer bridge.Close()
+
+	if err := bridge.Start(); err == nil || !strings.Contains(err.Error(), "secret") {
+		t.Fatalf("start = %v, want a missing secret error", err)
+	}
+}
+
+func TestBridgeRejectsOtherVersions(t *testing.T) {
+	bridge := newTestBridge(t, "a", newTestBus(t), core.BridgeConfig{Routes: []core.BridgeRoute{{Event: "*"}}})
+
+	left, right := connPair(t)
+	defer left.Close()
+	errs := make(chan error, 1)
+	go func() { errs <- bridge.ServeConn(right) }()
+
+	writeBridgeFrame(left, bridgeFrame{Version: BridgeProtocolVersion + 1, Type: "hello", Node: "future"})
+	if err := <-errs; err == nil || !strings.Contains(err.Error(), "version") {
+		t.Fatalf("serve = %v, want a version error", err)
+	}
+}
+
+func TestBridgeRejectsOversizedFrames(t *testing.T) {
+	bridge := newTestBridge(t, "a", newTestBus(t), core.BridgeConfig{Routes: []core.BridgeRoute{{Event: "*"}}})
+
+	left, right := connPair(t)
+	defer left.Close()
+	errs := make(chan error, 1)
+	go func() { errs <- bridge.ServeConn(right) }()
+
+	reader := bufio.NewReader(left)
+	if _, err := readBridgeFrame(reader); err != nil {
+		t.Fatalf("reading hello: %v", err)
+	}
+	writeBridgeFrame(left, bridgeFrame{Version: BridgeProtocolVersion, Type: "hello", Node: "peer"})
+
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], maxBridgeFrame+1)
+	left.Write(header[:])
+
+	if err := <-errs; err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("serve = %v, want an oversized frame error", err)
+	}
+}
+
+func TestBridgePublishesOnlyOptedInRoutes(t *testing.T) {
+	bridge := NewBridge("a", core.BridgeConfig{Routes: []core.BridgeRoute{
+		{Event: "dm_*", Publish: true},
+		{Event: "group_response"},
+		{Event: "group_request", Direction: "out", Publish: true},
+	}}, newTestBus(t), BackoffPolicy{})
+
+	for eventType, want := range map[core.EventType]bool{
+		"dm_response":    true,
+		"group_response": false,
+		"group_request":  false,
+	} {
+		if got := bridge.Publishes(eventType); got != want {
+			t.Errorf("Publishes(%s) = %v, want %v", eventType, got, want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"agent/core"
+	"log"
+	"sync"
+	"time"
+)
+
+// PublishFunc is a step in the publish chain
+type PublishFunc func(eventType core.EventType, message *core.BusMessage)
+
+// Middleware wraps the publish chain. It may inspect or change the message,
+// call next to let it through or return without calling it to drop it
+type Middleware func(next PublishFunc) PublishFunc
+
+// TraceMiddleware logs every published message and how long publishing took
+func TraceMiddleware(name string) Middleware {
+	return func(next PublishFunc) PublishFunc {
+		return func(eventType core.EventType, message *core.BusMessage) {
+			started := time.Now()
+			next(eventType, message)
+			log.Printf("🔎 [%s] %s from %s published in %s", name, eventType, message.SenderPublicKey, time.Since(started))
+		}
+	}
+}
+
+// FilterMiddleware drops messages for which keep returns false
+func FilterMiddleware(keep func(core.EventType, *core.BusMessage) bool) Middleware {
+	return func(next PublishFunc) PublishFunc {
+		return func(eventType core.EventType, message *core.BusMessage) {
+			if keep(eventType, message) {
+				next(eventType, message)
+			}
+		}
+	}
+}
+
+// RateLimitMiddleware drops messages from a key, such as a sender, once it
+// exceeds perSecond messages with bursts of up to burst
+func RateLimitMiddleware(perSecond float64, burst int, key func(*core.BusMessage) string) Middleware {
+	type bucket struct {
+		tokens float64
+		last   time.Time
+	}
+
+	var (
+		mu      sync.Mutex
+		buckets = make(map[string]*bucket)
+	)
+
+	allow := func(k string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		b, found := buckets[k]
+		if !found {
+			b = &bucket{tokens: float64(burst), last: now}
+			buckets[k] = b
+		}
+
+		b.tokens = min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*perSecond)
+		b.last = now
+		if b.tokens < 1 {
+			return false
+		}
+		b.tokens--
+		return true
+	}
+
+	return func(next PublishFunc) PublishFunc {
+		return func(eventType core.EventType, message *core.BusMessage) {
+			if k := key(message); allow(k) {
+				next(eventType, message)
+			} else {
+				log.Printf("🚦 Rate limited %s message from %s", eventType, k)
+			}
+		}
+	}
+}
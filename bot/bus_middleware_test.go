@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"agent/core"
+	"testing"
+)
+
+func TestMiddlewareRunsInOrderAndMayDrop(t *testing.T) {
+	bus := startTestBus(t, NewBusOptions(core.EventBusConfig{}))
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next PublishFunc) PublishFunc {
+			return func(eventType core.EventType, message *core.BusMessage) {
+				order = append(order, name)
+				next(eventType, message)
+			}
+		}
+	}
+	bus.Use(trace("first"), trace("second"))
+	bus.Use(FilterMiddleware(func(eventType core.EventType, message *core.BusMessage) bool {
+		return message.SenderPublicKey != "spammer"
+	}))
+
+	delivered := 0
+	bus.Subscribe(core.DMMessageEvent, func(message *core.BusMessage) { delivered++ })
+	bus.PublishSync(core.DMMessageEvent, &core.BusMessage{SenderPublicKey: "alice"})
+	bus.PublishSync(core.DMMessageEvent, &core.BusMessage{SenderPublicKey: "spammer"})
+
+	if got := len(order); got != 4 || order[0] != "first" || order[1] != "second" {
+		t.Fatalf("middleware ran as %v, want first then second for each message", order)
+	}
+	if delivered != 1 {
+		t.Fatalf("delivered %d messages, want only alice's", delivered)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	bus := startTestBus(t, NewBusOptions(core.EventBusConfig{}))
+	bus.Use(RateLimitMiddleware(0.001, 2, func(message *core.BusMessage) string { return message.SenderPublicKey }))
+
+	delivered := make(map[string]int)
+	bus.Subscribe(core.DMMessageEvent, func(message *core.BusMessage) { delivered[message.SenderPublicKey]++ })
+	for _, sender := range []string{"alice", "alice", "alice", "bob", "alice"} {
+		bus.PublishSync(core.DMMessageEvent, &core.BusMessage{SenderPublicKey: sender})
+	}
+
+	if delivered["alice"] != 2 || delivered["bob"] != 1 {
+		t.Fatalf("delivered %v, want alice limited to her burst of 2 and bob untouched", delivered)
+	}
+}
@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Cursor marks the last event a subscription processed
+type Cursor struct {
+	CreatedAt nostr.Timestamp `json:"created_at"`
+	EventID   string          `json:"event_id"`
+}
+
+// Before reports whether the event comes after the cursor and still needs processing
+func (c Cursor) Before(event *nostr.Event) bool {
+	if event.CreatedAt != c.CreatedAt {
+		return event.CreatedAt > c.CreatedAt
+	}
+	return event.ID != c.EventID
+}
+
+// CursorStore keeps per-subscription cursors so a restarted bot can catch up
+type CursorStore interface {
+	Load(subscription string) (Cursor, bool, error)
+	Save(subscription string, cursor Cursor) error
+}
+
+// FileCursorStore keeps the cursors of one bot in a JSON file
+type FileCursorStore struct {
+	mu      sync.Mutex
+	path    string
+	cursors map[string]Cursor
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// NewFileCursorStore opens (or lazily creates) the cursor file of a bot inside dir
+func NewFileCursorStore(dir, botName string) (*FileCursorStore, error) {
+	store := &FileCursorStore{
+		path:    filepath.Join(dir, unsafeFileChars.ReplaceAllString(botName, "_")+".json"),
+		cursors: make(map[string]Cursor),
+	}
+
+	data, err := os.ReadFile(store.path)
+	switch {
+	case os.IsNotExist(err):
+		return store, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read cursors: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &store.cursors); err != nil {
+		return nil, fmt.Errorf("failed to parse cursors in %s: %w", store.path, err)
+	}
+	return store, nil
+}
+
+func (s *FileCursorStore) Load(subscription string) (Cursor, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cursor, found := s.cursors[subscription]
+	return cursor, found, nil
+}
+
+// Save records the cursor and rewrites the file atomically
+func (s *FileCursorStore) Save(subscription string, cursor Cursor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cursors[subscription] = cursor
+
+	data, err := json.MarshalIndent(s.cursors, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// MemoryCursorStore keeps cursors for the lifetime of the process only
+type MemoryCursorStore struct {
+	mu      sync.Mutex
+	cursors map[string]Cursor
+}
+
+func NewMemoryCursorStore() *MemoryCursorStore {
+	return &MemoryCursorStore{cursors: make(map[string]Cursor)}
+}
+
+func (s *MemoryCursorStore) Load(subscription string) (Cursor, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cursor, found := s.cursors[subscription]
+	return cursor, found, nil
+}
+
+func (s *MemoryCursorStore) Save(subscription string, cursor Cursor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cursors[subscription] = cursor
+	return nil
+}
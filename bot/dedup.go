@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"agent/core"
+	"bufio"
+	"container/list"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+var (
+	ErrDuplicateEvent = errors.New("duplicate event")
+	ErrClockSkew      = errors.New("created_at outside the accepted window")
+	ErrInvalidEventID = errors.New("event id does not match its content")
+	ErrBadSignature   = errors.New("invalid event signature")
+)
+
+// Deduplicator guards listeners against processing an event twice. It
+// verifies each event, rejects skewed timestamps and remembers recent event
+// IDs in a bounded LRU that can survive restarts
+type Deduplicator struct {
+	mu       sync.Mutex
+	capacity int
+	ids      map[string]*list.Element
+	order    *list.List // Most recent at the front
+
+	maxPast   time.Duration // 0 accepts any age
+	maxFuture time.Duration // 0 accepts any future timestamp
+
+	path   string   // Append-only log of accepted IDs, empty when not persisted
+	log    *os.File // Open handle on path
+	logged int      // IDs in the log, compacted once it holds twice the capacity
+}
+
+// NewDeduplicator creates the deduplicator of a bot from its config
+func NewDeduplicator(config core.DedupConfig, botName string) (*Deduplicator, error) {
+	d := &Deduplicator{
+		capacity:  config.CapacityOrDefault(),
+		ids:       make(map[string]*list.Element),
+		order:     list.New(),
+		maxPast:   time.Duration(config.MaxPast) * time.Second,
+		maxFuture: config.MaxFutureDuration(),
+	}
+
+	if config.Persist {
+		d.path = filepath.Join(config.Directory(), unsafeFileChars.ReplaceAllString(botName, "_")+".ids")
+		if err := d.restore(); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Check verifies the event and records it as seen. It returns an error when
+// the event must not be processed
+func (d *Deduplicator) Check(event *nostr.Event) error {
+	if d.seen(event.ID) {
+		return ErrDuplicateEvent
+	}
+
+	now := time.Now()
+	created := event.CreatedAt.Time()
+	if d.maxFuture > 0 && created.After(now.Add(d.maxFuture)) {
+		return fmt.Errorf("%w: %s in the future", ErrClockSkew, created.Sub(now).Round(time.Second))
+	}
+	if d.maxPast > 0 && created.Before(now.Add(-d.maxPast)) {
+		return fmt.Errorf("%w: %s old", ErrClockSkew, now.Sub(created).Round(time.Second))
+	}
+
+	if !event.CheckID() {
+		return ErrInvalidEventID
+	}
+	if ok, err := event.CheckSignature(); err != nil || !ok {
+		return ErrBadSignature
+	}
+
+	if !d.add(event.ID) {
+		return ErrDuplicateEvent // Another relay delivered it while we were verifying
+	}
+	return nil
+}
+
+// Close flushes and closes the persisted log
+func (d *Deduplicator) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.log != nil {
+		d.log.Close()
+		d.log = nil
+	}
+}
+
+func (d *Deduplicator) seen(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, found := d.ids[id]
+	return found
+}
+
+// add records the ID, reporting false when it was already present
+func (d *Deduplicator) add(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if element, found := d.ids[id]; found {
+		d.order.MoveToFront(element)
+		return false
+	}
+
+	d.remember(id)
+
+	if d.log != nil {
+		fmt.Fprintln(d.log, id)
+		if d.logged++; d.logged >= 2*d.capacity {
+			if err := d.compact(); err != nil {
+				log.Printf("⚠️ Could not compact %s: %v", d.path, err)
+			}
+		}
+	}
+	return true
+}
+
+// remember inserts the ID, evicting the oldest ones past capacity. Callers hold d.mu
+func (d *Deduplicator) remember(id string) {
+	d.ids[id] = d.order.PushFront(id)
+
+	for d.order.Len() > d.capacity {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.ids, oldest.Value.(string))
+	}
+}
+
+// restore loads the persisted IDs and compacts the log down to capacity
+func (d *Deduplicator) restore() error {
+	if file, err := os.Open(d.path); err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if id := scanner.Text(); id != "" {
+				if _, found := d.ids[id]; !found {
+					d.remember(id)
+				}
+			}
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read %s: %w", d.path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to open %s: %w", d.path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.path), 0o755); err != nil {
+		return err
+	}
+	return d.compact()
+}
+
+// compact rewrites the log oldest first so it only holds what is still
+// remembered, and reopens it for appending. IDs evicted from the LRU would
+// otherwise pile up in the log of a bot that never restarts. Callers hold d.mu
+func (d *Deduplicator) compact() error {
+	tmp := d.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	for element := d.order.Back(); element != nil; element = element.Prev() {
+		fmt.Fprintln(writer, element.Value.(string))
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	file.Close()
+	if err := os.Rename(tmp, d.path); err != nil {
+		return err
+	}
+
+	if d.log != nil {
+		d.log.Close()
+	}
+	d.log, err = os.OpenFile(d.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	d.logged = d.order.Len()
+	return err
+}
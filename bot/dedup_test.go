@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"agent/core"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// signedEvents returns n events signed by a fresh key
+func signedEvents(t *testing.T, n int) []*nostr.Event {
+	t.Helper()
+
+	sk := nostr.GeneratePrivateKey()
+	events := make([]*nostr.Event, n)
+	for i := range events {
+		events[i] = &nostr.Event{Kind: nostr.KindTextNote, Content: strings.Repeat("x", i), CreatedAt: nostr.Now()}
+		if err := events[i].Sign(sk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return events
+}
+
+func TestDeduplicatorCompactsItsLog(t *testing.T) {
+	config := core.DedupConfig{Capacity: 3, Persist: true, Dir: t.TempDir()}
+	dedup, err := NewDeduplicator(config, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := signedEvents(t, 20)
+	for _, event := range events {
+		if err := dedup.Check(event); err != nil {
+			t.Fatalf("check: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(dedup.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines >= 2*config.Capacity {
+		t.Fatalf("log holds %d IDs, want fewer than %d", lines, 2*config.Capacity)
+	}
+	dedup.Close()
+
+	// The newest IDs survive a restart, the evicted ones are forgotten
+	restored, err := NewDeduplicator(config, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+
+	for _, event := range events[len(events)-config.Capacity:] {
+		if err := restored.Check(event); !errors.Is(err, ErrDuplicateEvent) {
+			t.Fatalf("recent event after restart: %v, want a duplicate", err)
+		}
+	}
+	if err := restored.Check(events[0]); err != nil {
+		t.Fatalf("evicted event after restart: %v, want it accepted", err)
+	}
+}
@@ -2,33 +2,556 @@ package bot
 
 import (
 	"agent/core"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"runtime/debug"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// ErrNoResponders is returned by Request when no handler matches the event type
+var ErrNoResponders = errors.New("no handler subscribed")
+
+// defaultRequestTimeout bounds a Request whose context has no deadline
+const defaultRequestTimeout = 10 * time.Second
+
+// DeliveryMode decides which worker handles a message
+type DeliveryMode string
+
+const (
+	// DeliveryOrdered sends messages with the same key to the same worker,
+	// so each handler sees them in publish order
+	DeliveryOrdered DeliveryMode = "ordered"
+
+	// DeliveryUnordered lets any free worker take the next message
+	DeliveryUnordered DeliveryMode = "unordered"
+)
+
+// OverflowPolicy decides what Publish does when a queue is full
+type OverflowPolicy string
+
+const (
+	OverflowBlock OverflowPolicy = "block" // Wait for room, up to BlockTimeout, then drop
+	OverflowDrop  OverflowPolicy = "drop"  // Drop the message at once
+)
+
+// BusOptions configures the worker pool behind an EventBus
+type BusOptions struct {
+	Mode         DeliveryMode
+	Key          func(*core.BusMessage) string // Ordering key for DeliveryOrdered
+	Workers      int
+	QueueSize    int // Per worker
+	Overflow     OverflowPolicy
+	BlockTimeout time.Duration
+}
+
+// NewBusOptions builds bus options from config, filling in defaults
+func NewBusOptions(config core.EventBusConfig) BusOptions {
+	options := BusOptions{
+		Mode:         DeliveryMode(config.Mode),
+		Key:          func(message *core.BusMessage) string { return message.SenderPublicKey },
+		Workers:      config.Workers,
+		QueueSize:    config.QueueSize,
+		Overflow:     OverflowPolicy(config.Overflow),
+		BlockTimeout: time.Duration(config.BlockTimeout) * time.Second,
+	}
+	if options.Mode == "" {
+		options.Mode = DeliveryOrdered
+	}
+	if config.OrderBy == "channel" {
+		options.Key = func(message *core.BusMessage) string { return message.ChannelID }
+	}
+	if options.Workers <= 0 {
+		options.Workers = 8
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = 256
+	}
+	if options.Overflow == "" {
+		options.Overflow = OverflowBlock
+	}
+	if options.BlockTimeout <= 0 {
+		options.BlockTimeout = 5 * time.Second
+	}
+	return options
+}
+
+// BusStats is a snapshot of an EventBus, for spotting handlers that fall behind
+type BusStats struct {
+	Mode          DeliveryMode                  `json:"mode"`
+	Workers       int                           `json:"workers"`
+	Queued        int                           `json:"queued"` // Messages waiting for a worker
+	QueueCapacity int                           `json:"queue_capacity"`
+	Topics        map[core.EventType]TopicStats `json:"topics"`
+}
+
+// TopicStats counts deliveries of a single event type
+type TopicStats struct {
+	Handlers   int           `json:"handlers"`
+	Delivered  uint64        `json:"delivered"`
+	Dropped    uint64        `json:"dropped"`
+	Panics     uint64        `json:"panics"`
+	AvgWait    time.Duration `json:"avg_wait"`    // Time spent queued
+	AvgLatency time.Duration `json:"avg_latency"` // Time spent in handlers
+	MaxLatency time.Duration `json:"max_latency"`
+}
+
 type EventBus struct {
-	subscribers map[core.EventType][]func(*core.BusMessage)
+	subscribers map[core.EventType][]*Subscription // Exact event types
+	wildcards   []*Subscription                    // Patterns such as "*" or "dm_*"
+	middleware  []Middleware
 	lock        sync.RWMutex
+
+	pendingLock sync.Mutex
+	pending     map[string]chan *core.BusMessage // Requests waiting for a reply, by correlation ID
+
+	options BusOptions
+	queues  []chan delivery // One per worker when ordered, a single shared one otherwise
+	done    chan struct{}   // Closed by Close to stop the workers
+	closed  sync.Once
+
+	running inflight // Deliveries queued or running
+
+	statsLock sync.Mutex
+	stats     map[core.EventType]*topicCounters
+}
+
+// Subscription is a handler registered on the bus. Unsubscribe removes it
+type Subscription struct {
+	pattern core.EventType
+	handler func(core.EventType, *core.BusMessage)
+	removed atomic.Bool
+	bus     *EventBus
+}
+
+// Unsubscribe stops further deliveries, including ones already queued
+func (s *Subscription) Unsubscribe() {
+	if s.removed.Swap(true) {
+		return
+	}
+	s.bus.remove(s)
+}
+
+// matches reports whether the subscription pattern covers the event type
+func (s *Subscription) matches(eventType core.EventType) bool {
+	return matchEventType(s.pattern, eventType)
+}
+
+// matchEventType reports whether an exact or wildcard pattern covers the event type
+func matchEventType(pattern, eventType core.EventType) bool {
+	if prefix, wildcard := strings.CutSuffix(string(pattern), "*"); wildcard {
+		return strings.HasPrefix(string(eventType), prefix)
+	}
+	return pattern == eventType
+}
+
+// delivery is one message on its way to one handler
+type delivery struct {
+	eventType    core.EventType
+	subscription *Subscription
+	message      *core.BusMessage
+	queued       time.Time
 }
 
-func NewEventBus() *EventBus {
-	return &EventBus{
-		subscribers: make(map[core.EventType][]func(*core.BusMessage)),
+type topicCounters struct {
+	delivered, dropped, panics uint64
+	wait, latency, maxLatency  time.Duration
+}
+
+// NewEventBus starts the bus workers
+func NewEventBus(options BusOptions) *EventBus {
+	bus := &EventBus{
+		subscribers: make(map[core.EventType][]*Subscription),
+		pending:     make(map[string]chan *core.BusMessage),
+		options:     options,
+		done:        make(chan struct{}),
+		stats:       make(map[core.EventType]*topicCounters),
+	}
+
+	if options.Mode == DeliveryUnordered {
+		bus.queues = []chan delivery{make(chan delivery, options.Workers*options.QueueSize)}
+	} else {
+		bus.queues = make([]chan delivery, options.Workers)
+		for i := range bus.queues {
+			bus.queues[i] = make(chan delivery, options.QueueSize)
+		}
+	}
+
+	for i := 0; i < options.Workers; i++ {
+		go bus.work(bus.queues[i%len(bus.queues)])
+	}
+	return bus
+}
+
+// Subscribe registers a handler for an event type. A type ending in "*" is a
+// wildcard: "*" receives every event and "dm_*" every event starting with "dm_"
+func (bus *EventBus) Subscribe(eventType core.EventType, handler func(*core.BusMessage)) *Subscription {
+	return bus.SubscribeEvents(eventType, func(_ core.EventType, message *core.BusMessage) {
+		handler(message)
+	})
+}
+
+// SubscribeEvents is Subscribe for handlers that need the event type, such
+// as loggers and auditors on a wildcard
+func (bus *EventBus) SubscribeEvents(eventType core.EventType, handler func(core.EventType, *core.BusMessage)) *Subscription {
+	subscription := &Subscription{
+		pattern: eventType,
+		handler: handler,
+		bus:     bus,
+	}
+
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+
+	if strings.HasSuffix(string(eventType), "*") {
+		bus.wildcards = append(bus.wildcards, subscription)
+	} else {
+		bus.subscribers[eventType] = append(bus.subscribers[eventType], subscription)
 	}
+	return subscription
 }
 
-func (bus *EventBus) Subscribe(eventType core.EventType, handler func(*core.BusMessage)) {
+// Use appends middleware around Publish and PublishSync. The first one added
+// runs first and sees every message
+func (bus *EventBus) Use(middleware ...Middleware) {
 	bus.lock.Lock()
 	defer bus.lock.Unlock()
-	bus.subscribers[eventType] = append(bus.subscribers[eventType], handler)
+	bus.middleware = append(bus.middleware, middleware...)
 }
 
+// Publish queues the message for every matching handler and returns without
+// waiting for them
 func (bus *EventBus) Publish(eventType core.EventType, message *core.BusMessage) {
+	bus.through(bus.dispatch)(eventType, message)
+}
+
+// PublishSync runs every matching handler in turn before returning,
+// preserving the order of consecutive messages
+func (bus *EventBus) PublishSync(eventType core.EventType, message *core.BusMessage) {
+	bus.through(bus.dispatchSync)(eventType, message)
+}
+
+// Request publishes the message with a new correlation ID and waits for a
+// handler to answer it with Reply. Without a context deadline it gives up
+// after ten seconds. Catch-all "*" subscribers only observe traffic and do
+// not count as responders
+func (bus *EventBus) Request(ctx context.Context, eventType core.EventType, message *core.BusMessage) (*core.BusMessage, error) {
+	responders := 0
+	for _, subscription := range bus.matching(eventType) {
+		if subscription.pattern != "*" {
+			responders++
+		}
+	}
+	if responders == 0 {
+		return nil, fmt.Errorf("%w to %s", ErrNoResponders, eventType)
+	}
+
+	if _, found := ctx.Deadline(); !found {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultRequestTimeout)
+		defer cancel()
+	}
+
+	request := *message
+	request.CorrelationID = newCorrelationID()
+	request.ReplyTo = core.ReplyEvent
+
+	replies := make(chan *core.BusMessage, 1)
+	bus.pendingLock.Lock()
+	bus.pending[request.CorrelationID] = replies
+	bus.pendingLock.Unlock()
+
+	defer func() {
+		bus.pendingLock.Lock()
+		delete(bus.pending, request.CorrelationID)
+		bus.pendingLock.Unlock()
+	}()
+
+	bus.Publish(eventType, &request)
+
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no reply to %s %s: %w", eventType, request.CorrelationID, ctx.Err())
+	}
+}
+
+// Reply answers a message sent with Request. The reply is also published on
+// the request's ReplyTo type, core.ReplyEvent by default, so auditors and
+// requesters that already gave up can still see it
+func (bus *EventBus) Reply(request *core.BusMessage, reply *core.BusMessage) {
+	reply.CorrelationID = request.CorrelationID
+
+	bus.pendingLock.Lock()
+	replies, waiting := bus.pending[request.CorrelationID]
+	bus.pendingLock.Unlock()
+
+	if waiting {
+		select {
+		case replies <- reply:
+		default: // Someone answered first
+		}
+	}
+
+	replyTo := request.ReplyTo
+	if replyTo == "" {
+		replyTo = core.ReplyEvent
+	}
+	bus.Publish(replyTo, reply)
+}
+
+// Drain waits for every queued or running delivery to finish, including
+// those published while draining, or for the context to end
+func (bus *EventBus) Drain(ctx context.Context) error {
+	if running, err := bus.running.wait(ctx); err != nil {
+		return fmt.Errorf("%d handlers still running: %w", running, err)
+	}
+	return nil
+}
+
+// Close stops the workers. Messages published afterwards are dropped
+func (bus *EventBus) Close() {
+	bus.closed.Do(func() { close(bus.done) })
+}
+
+// Stats returns queue depth and per-topic delivery counters
+func (bus *EventBus) Stats() BusStats {
+	stats := BusStats{
+		Mode:    bus.options.Mode,
+		Workers: bus.options.Workers,
+		Topics:  make(map[core.EventType]TopicStats),
+	}
+	for _, queue := range bus.queues {
+		stats.Queued += len(queue)
+		stats.QueueCapacity += cap(queue)
+	}
+
+	bus.lock.RLock()
+	for eventType, subscriptions := range bus.subscribers {
+		stats.Topics[eventType] = TopicStats{Handlers: len(subscriptions)}
+	}
+	for _, subscription := range bus.wildcards {
+		topic := stats.Topics[subscription.pattern]
+		topic.Handlers++
+		stats.Topics[subscription.pattern] = topic
+	}
+	bus.lock.RUnlock()
+
+	bus.statsLock.Lock()
+	defer bus.statsLock.Unlock()
+
+	for eventType, counters := range bus.stats {
+		topic := stats.Topics[eventType]
+		topic.Delivered = counters.delivered
+		topic.Dropped = counters.dropped
+		topic.Panics = counters.panics
+		topic.MaxLatency = counters.maxLatency
+		if counters.delivered > 0 {
+			topic.AvgWait = counters.wait / time.Duration(counters.delivered)
+			topic.AvgLatency = counters.latency / time.Duration(counters.delivered)
+		}
+		stats.Topics[eventType] = topic
+	}
+	return stats
+}
+
+// through wraps the final publish step in the middleware chain
+func (bus *EventBus) through(final PublishFunc) PublishFunc {
+	bus.lock.RLock()
+	middleware := bus.middleware
+	bus.lock.RUnlock()
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		final = middleware[i](final)
+	}
+	return final
+}
+
+// dispatch queues a delivery for every matching subscription
+func (bus *EventBus) dispatch(eventType core.EventType, message *core.BusMessage) {
+	subscriptions := bus.matching(eventType)
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	queue := bus.queueFor(message)
+	for _, subscription := range subscriptions {
+		bus.enqueue(queue, delivery{eventType: eventType, subscription: subscription, message: message, queued: time.Now()})
+	}
+}
+
+// dispatchSync runs every matching subscription on the calling goroutine
+func (bus *EventBus) dispatchSync(eventType core.EventType, message *core.BusMessage) {
+	bus.running.add(1)
+	defer bus.running.add(-1)
+
+	for _, subscription := range bus.matching(eventType) {
+		bus.deliver(delivery{eventType: eventType, subscription: subscription, message: message, queued: time.Now()})
+	}
+}
+
+// matching lists the exact and wildcard subscriptions for the event type
+func (bus *EventBus) matching(eventType core.EventType) []*Subscription {
 	bus.lock.RLock()
 	defer bus.lock.RUnlock()
 
-	if handlers, found := bus.subscribers[eventType]; found {
-		for _, handler := range handlers {
-			go handler(message) // Asynchronous execution
+	subscriptions := append([]*Subscription(nil), bus.subscribers[eventType]...)
+	for _, subscription := range bus.wildcards {
+		if subscription.matches(eventType) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions
+}
+
+func (bus *EventBus) remove(subscription *Subscription) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+
+	without := func(subscriptions []*Subscription) []*Subscription {
+		kept := subscriptions[:0:0]
+		for _, s := range subscriptions {
+			if s != subscription {
+				kept = append(kept, s)
+			}
 		}
+		return kept
 	}
+
+	if strings.HasSuffix(string(subscription.pattern), "*") {
+		bus.wildcards = without(bus.wildcards)
+	} else if kept := without(bus.subscribers[subscription.pattern]); len(kept) > 0 {
+		bus.subscribers[subscription.pattern] = kept
+	} else {
+		delete(bus.subscribers, subscription.pattern)
+	}
+}
+
+// queueFor picks the queue of the worker that must handle the message
+func (bus *EventBus) queueFor(message *core.BusMessage) chan delivery {
+	if len(bus.queues) == 1 {
+		return bus.queues[0]
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(bus.options.Key(message)))
+	return bus.queues[hash.Sum32()%uint32(len(bus.queues))]
+}
+
+// enqueue hands the delivery to a worker, applying the overflow policy when its queue is full
+func (bus *EventBus) enqueue(queue chan delivery, d delivery) {
+	bus.running.add(1)
+
+	select {
+	case queue <- d:
+		return
+	case <-bus.done:
+		bus.dropped(d, "bus closed")
+		return
+	default:
+	}
+
+	if bus.options.Overflow == OverflowBlock {
+		timer := time.NewTimer(bus.options.BlockTimeout)
+		defer timer.Stop()
+
+		select {
+		case queue <- d:
+			return
+		case <-bus.done:
+			bus.dropped(d, "bus closed")
+			return
+		case <-timer.C:
+		}
+	}
+	bus.dropped(d, "queue full")
+}
+
+func (bus *EventBus) dropped(d delivery, reason string) {
+	bus.running.add(-1)
+	bus.record(d.eventType, func(c *topicCounters) { c.dropped++ })
+	log.Printf("📪 EventBus dropped [%s] message: %s", d.eventType, reason)
+}
+
+// work runs deliveries from the queue until the bus is closed
+func (bus *EventBus) work(queue chan delivery) {
+	for {
+		select {
+		case d := <-queue:
+			bus.deliver(d)
+			bus.running.add(-1)
+		case <-bus.done:
+			return
+		}
+	}
+}
+
+// deliver runs one handler, turning a panic into a dead letter
+func (bus *EventBus) deliver(d delivery) {
+	if d.subscription.removed.Load() {
+		return
+	}
+
+	started := time.Now()
+
+	defer func() {
+		latency := time.Since(started)
+		recovered := recover()
+
+		bus.record(d.eventType, func(c *topicCounters) {
+			c.delivered++
+			c.wait += started.Sub(d.queued)
+			c.latency += latency
+			c.maxLatency = max(c.maxLatency, latency)
+			if recovered != nil {
+				c.panics++
+			}
+		})
+
+		if recovered != nil {
+			log.Printf("💥 EventBus handler for [%s] panicked: %v\n%s", d.eventType, recovered, debug.Stack())
+			bus.deadLetter(d, recovered)
+		}
+	}()
+
+	d.subscription.handler(d.eventType, d.message)
+}
+
+// deadLetter republishes a message whose handler panicked on core.DeadLetterEvent.
+// The copy keeps the original text and describes the failure in its metadata
+func (bus *EventBus) deadLetter(d delivery, reason interface{}) {
+	if d.eventType == core.DeadLetterEvent {
+		return // Never loop on a failing dead letter handler
+	}
+
+	letter := *d.message
+	letter.Payload.Kind = "dead_letter"
+	letter.Payload.Metadata = fmt.Sprintf("%s: %v", d.eventType, reason)
+	bus.Publish(core.DeadLetterEvent, &letter)
+}
+
+func (bus *EventBus) record(eventType core.EventType, update func(*topicCounters)) {
+	bus.statsLock.Lock()
+	defer bus.statsLock.Unlock()
+
+	counters, found := bus.stats[eventType]
+	if !found {
+		counters = &topicCounters{}
+		bus.stats[eventType] = counters
+	}
+	update(counters)
+}
+
+func newCorrelationID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
 }
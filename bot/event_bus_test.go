@@ -0,0 +1,253 @@
+package bot
+
+import (
+	"agent/core"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startTestBus starts a bus with the given options, closed when the test ends
+func startTestBus(t *testing.T, options BusOptions) *EventBus {
+	t.Helper()
+
+	if options.Key == nil {
+		options.Key = func(message *core.BusMessage) string { return message.SenderPublicKey }
+	}
+	bus := NewEventBus(options)
+	t.Cleanup(bus.Close)
+	return bus
+}
+
+func drainBus(t *testing.T, bus *EventBus) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bus.Drain(ctx); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+}
+
+func TestEventBusOrderedDeliveryKeepsSenderOrder(t *testing.T) {
+	bus := startTestBus(t, BusOptions{Mode: DeliveryOrdered, Workers: 4, QueueSize: 300, Overflow: OverflowBlock, BlockTimeout: time.Second})
+
+	var (
+		mu       sync.Mutex
+		received = make(map[string][]int64)
+	)
+	bus.Subscribe(core.DMMessageEvent, func(message *core.BusMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+		received[message.SenderPublicKey] = append(received[message.SenderPublicKey], message.Timestamp)
+	})
+
+	senders := []string{"alice", "bob", "carol"}
+	for i := int64(0); i < 100; i++ {
+		for _, sender := range senders {
+			bus.Publish(core.DMMessageEvent, &core.BusMessage{SenderPublicKey: sender, Timestamp: i})
+		}
+	}
+	drainBus(t, bus)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, sender := range senders {
+		got := received[sender]
+		if len(got) != 100 {
+			t.Fatalf("%s received %d messages, want 100", sender, len(got))
+		}
+		for i, timestamp := range got {
+			if timestamp != int64(i) {
+				t.Fatalf("%s received message %d at position %d", sender, timestamp, i)
+			}
+		}
+	}
+}
+
+func TestEventBusUnorderedDeliveryRunsInParallel(t *testing.T) {
+	const workers = 4
+	bus := startTestBus(t, BusOptions{Mode: DeliveryUnordered, Workers: workers, QueueSize: 10, Overflow: OverflowBlock, BlockTimeout: time.Second})
+
+	// Every handler waits for the others, which only works when messages
+	// of a single sender are spread over all workers
+	var started sync.WaitGroup
+	started.Add(workers)
+	together := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(together)
+	}()
+
+	timedOut := make(chan struct{}, workers)
+	bus.Subscribe(core.DMMessageEvent, func(message *core.BusMessage) {
+		started.Done()
+		select {
+		case <-together:
+		case <-time.After(5 * time.Second):
+			timedOut <- struct{}{}
+		}
+	})
+
+	for i := 0; i < workers; i++ {
+		bus.Publish(core.DMMessageEvent, &core.BusMessage{SenderPublicKey: "alice"})
+	}
+	drainBus(t, bus)
+
+	if len(timedOut) > 0 {
+		t.Fatal("messages of one sender were not handled in parallel")
+	}
+}
+
+func TestEventBusOverflow(t *testing.T) {
+	tests := []struct {
+		name        string
+		overflow    OverflowPolicy
+		release     time.Duration // When the blocked handler lets go
+		wantDropped uint64
+	}{
+		{"drop", OverflowDrop, 100 * time.Millisecond, 1},
+		{"block until there is room", OverflowBlock, 50 * time.Millisecond, 0},
+		{"block until the timeout", OverflowBlock, time.Second, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := startTestBus(t, BusOptions{Mode: DeliveryOrdered, Workers: 1, QueueSize: 1, Overflow: tt.overflow, BlockTimeout: 200 * time.Millisecond})
+
+			running, release := make(chan struct{}, 3), make(chan struct{})
+			bus.Subscribe(core.DMMessageEvent, func(message *core.BusMessage) {
+				running <- struct{}{}
+				<-release
+			})
+
+			// The first message occupies the worker and the second fills its queue
+			bus.Publish(core.DMMessageEvent, &core.BusMessage{})
+			<-running
+			bus.Publish(core.DMMessageEvent, &core.BusMessage{})
+
+			time.AfterFunc(tt.release, func() { close(release) })
+			bus.Publish(core.DMMessageEvent, &core.BusMessage{})
+			drainBus(t, bus)
+
+			topic := bus.Stats().Topics[core.DMMessageEvent]
+			if topic.Dropped != tt.wantDropped || topic.Delivered != 3-tt.wantDropped {
+				t.Fatalf("delivered %d and dropped %d, want %d dropped of 3", topic.Delivered, topic.Dropped, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestEventBusTurnsPanicsIntoDeadLetters(t *testing.T) {
+	bus := startTestBus(t, BusOptions{Mode: DeliveryOrdered, Workers: 2, QueueSize: 10, Overflow: OverflowBlock, BlockTimeout: time.Second})
+
+	letters := make(chan *core.BusMessage, 2)
+	bus.Subscribe(core.DMMessageEvent, func(message *core.BusMessage) { panic("boom") })
+	bus.Subscribe(core.DeadLetterEvent, func(message *core.BusMessage) {
+		letters <- message
+		panic("dead letter handlers may fail too")
+	})
+
+	message := &core.BusMessage{SenderPublicKey: "alice", Payload: core.ContentStructure{Text: "hello"}}
+	bus.Publish(core.DMMessageEvent, message)
+	drainBus(t, bus)
+
+	if len(letters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(letters))
+	}
+	letter := <-letters
+	if letter == message || letter.Payload.Text != "hello" || letter.Payload.Kind != "dead_letter" || !strings.Contains(letter.Payload.Metadata, "dm_message: boom") {
+		t.Fatalf("dead letter = %+v, want a copy describing the panic", letter.Payload)
+	}
+	if panics := bus.Stats().Topics[core.DMMessageEvent].Panics; panics != 1 {
+		t.Fatalf("counted %d panics, want 1", panics)
+	}
+}
+
+func TestEventBusWildcardsAndUnsubscribe(t *testing.T) {
+	bus := startTestBus(t, BusOptions{Mode: DeliveryOrdered, Workers: 2, QueueSize: 10, Overflow: OverflowBlock, BlockTimeout: time.Second})
+
+	var (
+		mu   sync.Mutex
+		seen []string
+	)
+	record := func(name string) func(core.EventType, *core.BusMessage) {
+		return func(eventType core.EventType, message *core.BusMessage) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen = append(seen, name+":"+string(eventType))
+		}
+	}
+	bus.SubscribeEvents("*", record("all"))
+	bus.SubscribeEvents("dm_*", record("dm"))
+	exact := bus.SubscribeEvents(core.DMMessageEvent, record("exact"))
+
+	bus.PublishSync(core.DMMessageEvent, &core.BusMessage{})
+	bus.PublishSync(core.GroupMessageEvent, &core.BusMessage{})
+	exact.Unsubscribe()
+	exact.Unsubscribe()
+	bus.PublishSync(core.DMMessageEvent, &core.BusMessage{})
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := "exact:dm_message all:dm_message dm:dm_message all:group_message all:dm_message dm:dm_message"
+	if got := strings.Join(seen, " "); got != want {
+		t.Fatalf("deliveries = %s, want %s", got, want)
+	}
+}
+
+func TestEventBusRequestReply(t *testing.T) {
+	bus := startTestBus(t, BusOptions{Mode: DeliveryOrdered, Workers: 2, QueueSize: 10, Overflow: OverflowBlock, BlockTimeout: time.Second})
+
+	replies := make(chan *core.BusMessage, 1)
+	bus.Subscribe(core.ReplyEvent, func(message *core.BusMessage) { replies <- message })
+	bus.Subscribe("*", func(message *core.BusMessage) {}) // Observers do not answer
+	bus.Subscribe("ping", func(request *core.BusMessage) {
+		bus.Reply(request, &core.BusMessage{Payload: core.ContentStructure{Text: "pong " + request.Payload.Text}})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := bus.Request(ctx, "ping", &core.BusMessage{Payload: core.ContentStructure{Text: "1"}})
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if reply.Payload.Text != "pong 1" || reply.CorrelationID == "" {
+		t.Fatalf("reply = %+v, want pong 1 with a correlation ID", reply)
+	}
+
+	// The reply is also published for anyone watching replies
+	select {
+	case published := <-replies:
+		if published.CorrelationID != reply.CorrelationID {
+			t.Fatalf("published reply %s, want %s", published.CorrelationID, reply.CorrelationID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reply was not published")
+	}
+}
+
+func TestEventBusRequestFailures(t *testing.T) {
+	bus := startTestBus(t, BusOptions{Mode: DeliveryOrdered, Workers: 2, QueueSize: 10, Overflow: OverflowBlock, BlockTimeout: time.Second})
+	bus.Subscribe("*", func(message *core.BusMessage) {})
+	bus.Subscribe("silent", func(message *core.BusMessage) {})
+
+	if _, err := bus.Request(context.Background(), "ping", &core.BusMessage{}); !errors.Is(err, ErrNoResponders) {
+		t.Fatalf("request without responders = %v, want ErrNoResponders", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := bus.Request(ctx, "silent", &core.BusMessage{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unanswered request = %v, want a deadline error", err)
+	}
+
+	bus.pendingLock.Lock()
+	defer bus.pendingLock.Unlock()
+	if len(bus.pending) != 0 {
+		t.Fatalf("%d requests still pending", len(bus.pending))
+	}
+}
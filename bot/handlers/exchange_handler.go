@@ -16,6 +16,16 @@ type ExchangeHandler struct {
 	encodedPublicKey string
 }
 
+func init() {
+	bot.RegisterHandler("ExchangeHandler", func(config core.BotConfig, options core.ComponentOptions, deps bot.HandlerDeps) (bot.EventHandler, error) {
+		return &ExchangeHandler{
+			ChannelID: config.ChannelID,
+			Manager:   deps.Manager,
+			Bot:       deps.Bot,
+		}, nil
+	})
+}
+
 // ✅ Subscribe to events
 func (h *ExchangeHandler) Subscribe(eventBus *bot.EventBus) {
 	if eventBus == nil {
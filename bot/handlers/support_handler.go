@@ -13,6 +13,12 @@ type SupportHandler struct {
 	EventBus *bot.EventBus
 }
 
+func init() {
+	bot.RegisterHandler("SupportHandler", func(config core.BotConfig, options core.ComponentOptions, deps bot.HandlerDeps) (bot.EventHandler, error) {
+		return &SupportHandler{}, nil
+	})
+}
+
 func (h *SupportHandler) Subscribe(eventBus *bot.EventBus) {
 	log.Println("✅ Subscribed")
 	h.EventBus = eventBus
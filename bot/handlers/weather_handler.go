@@ -14,6 +14,12 @@ type GroupHandler struct {
 	EventBus  *bot.EventBus
 }
 
+func init() {
+	bot.RegisterHandler("GroupHandler", func(config core.BotConfig, options core.ComponentOptions, deps bot.HandlerDeps) (bot.EventHandler, error) {
+		return &GroupHandler{ChannelID: config.ChannelID}, nil
+	})
+}
+
 func (h *GroupHandler) Subscribe(eventBus *bot.EventBus) {
 	log.Println("✅ Subscribed")
 	h.EventBus = eventBus
@@ -15,6 +15,12 @@ type WelcomeHandler struct {
 	EventBus  *bot.EventBus
 }
 
+func init() {
+	bot.RegisterHandler("WelcomeHandler", func(config core.BotConfig, options core.ComponentOptions, deps bot.HandlerDeps) (bot.EventHandler, error) {
+		return &WelcomeHandler{ChannelID: config.ChannelID}, nil
+	})
+}
+
 func (h *WelcomeHandler) Subscribe(eventBus *bot.EventBus) {
 	log.Println("✅ Subscribed")
 	h.EventBus = eventBus
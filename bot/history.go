@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"agent/bot/programs"
+	"sync"
+	"time"
+)
+
+// Execution is one program run recorded in a bot's history
+type Execution struct {
+	At      time.Time       `json:"at"`
+	EventID string          `json:"event_id,omitempty"` // Nostr event that triggered the run
+	Result  programs.Result `json:"result"`
+}
+
+// ExecutionHistory keeps the most recent program runs of a bot and counts
+// every run by status
+type ExecutionHistory struct {
+	mu      sync.Mutex
+	entries []Execution // Ring buffer, next is the oldest once full
+	next    int
+	full    bool
+	counts  map[programs.Status]uint64
+}
+
+// NewExecutionHistory creates a history holding up to capacity runs
+func NewExecutionHistory(capacity int) *ExecutionHistory {
+	return &ExecutionHistory{
+		entries: make([]Execution, capacity),
+		counts:  make(map[programs.Status]uint64),
+	}
+}
+
+// Record adds a run, evicting the oldest one when the history is full
+func (h *ExecutionHistory) Record(execution Execution) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.counts[execution.Result.Status]++
+
+	if len(h.entries) == 0 {
+		return
+	}
+	h.entries[h.next] = execution
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Recent returns up to limit runs, newest first, keeping only the given
+// status unless it is empty. A limit of 0 returns everything kept
+func (h *ExecutionHistory) Recent(status programs.Status, limit int) []Execution {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	size := h.next
+	if h.full {
+		size = len(h.entries)
+	}
+
+	executions := []Execution{}
+	for i := 1; i <= size; i++ {
+		execution := h.entries[(h.next-i+len(h.entries))%len(h.entries)]
+		if status != "" && execution.Result.Status != status {
+			continue
+		}
+		executions = append(executions, execution)
+		if limit > 0 && len(executions) == limit {
+			break
+		}
+	}
+	return executions
+}
+
+// Counts returns how many runs ended with each status since the bot started
+func (h *ExecutionHistory) Counts() map[programs.Status]uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	counts := make(map[programs.Status]uint64, len(h.counts))
+	for status, count := range h.counts {
+		counts[status] = count
+	}
+	return counts
+}
@@ -4,91 +4,86 @@ import (
 	"agent/bot"
 	"agent/core"
 	"encoding/json"
+	"fmt"
 	"log"
 
 	"github.com/nbd-wtf/go-nostr"
-	"github.com/nbd-wtf/go-nostr/nip04"
 	"github.com/nbd-wtf/go-nostr/nip19"
 )
 
-// DMListener handles direct message events
-type DMListener struct{}
+// DMListenerOptions are the typed `listener_options` of a DMListener
+type DMListenerOptions struct {
+	Limit int `yaml:"limit"`
+}
 
-// StartListening starts listening for direct messages
-func (listener *DMListener) StartListening(b *bot.BaseBot) {
-	relay := b.Relay
-	filters := listener.Filters(b)
+// DMListener handles direct message events
+type DMListener struct {
+	Options DMListenerOptions
+}
 
-	sub, err := relay.Subscribe(b.Context, filters)
-	if err != nil {
-		log.Printf("❌ Subscription failed: %v", err)
-		return
-	}
-	defer sub.Unsub()
-
-	var storedEvents []*nostr.Event
-	processingStoredEvents := false
-
-	for {
-		select {
-		case event, ok := <-sub.Events:
-			if !ok {
-				log.Println("🚫 Subscription closed, reconnecting...")
-				b.Relay.Close()
-				// return fmt.Errorf("subscription closed")
-			}
-
-			if !processingStoredEvents {
-				storedEvents = append(storedEvents, event)
-			} else if b.IsActiveListener {
-				listener.ProcessEvent(b, event)
-			}
-
-		case <-sub.EndOfStoredEvents:
-			if !processingStoredEvents {
-				log.Println("📥 Processing pending events...")
-				for i := len(storedEvents) - 1; i >= 0; i-- {
-					// bot.handleEvent(storedEvents[i])
-				}
-				storedEvents = nil
-				processingStoredEvents = true
-				b.IsActiveListener = true
-				log.Printf("👂 [%s] listening ", b.Config.Name)
-			}
-		case <-relay.Context().Done():
-			listener.HandleConnectionLoss(b)
-			return
+func init() {
+	bot.RegisterListener("DMListener", func(config core.BotConfig, options core.ComponentOptions) (bot.EventListener, error) {
+		listener := &DMListener{Options: DMListenerOptions{Limit: 50}}
+		if err := options.Decode(&listener.Options); err != nil {
+			return nil, err
 		}
+		return listener, nil
+	})
+}
+
+// StartListening starts listening for direct messages, catching up from the stored cursor
+func (listener *DMListener) StartListening(b *bot.BaseBot) error {
+	sub := &subscription{
+		name:     "dm",
+		filters:  listener.Filters(b),
+		decode:   listener.decode,
+		listener: listener,
 	}
+	return sub.run(b)
 }
 
 // ProcessEvent handles incoming direct message events
 func (listener *DMListener) ProcessEvent(b *bot.BaseBot, event *nostr.Event) {
+	if !accept(b, event) {
+		return
+	}
+
+	eventType, message, err := listener.decode(b, event)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	// 📩 Pass the event to EventBus
+	b.EventBus.Publish(eventType, message)
+}
+
+// decode decrypts a direct message into a bus message
+func (listener *DMListener) decode(b *bot.BaseBot, event *nostr.Event) (core.EventType, *core.BusMessage, error) {
 	// 🔑 Decrypt the incoming message
-	shared, _ := nip04.ComputeSharedSecret(event.PubKey, b.SecretKey)
 	npub, _ := nip19.EncodePublicKey(event.PubKey)
 
-	plaintext, err := nip04.Decrypt(event.Content, shared)
+	plaintext, err := b.Signer.NIP04Decrypt(b.Context, event.PubKey, event.Content)
 	if err != nil {
-		log.Printf("❌ Decryption failed: %v", err)
-		return
+		return "", nil, fmt.Errorf("decryption failed: %w", err)
 	}
 
 	log.Printf("🔓 Decrypted message: %s", plaintext)
 
 	var message core.ContentStructure
 	if err := json.Unmarshal([]byte(plaintext), &message); err != nil {
-		log.Printf("❌ Failed to unmarshal message: %v", err)
-		return
+		return "", nil, fmt.Errorf("failed to unmarshal message: %w", err)
 	}
 
 	log.Printf("💬 [DM from %s]: %s", npub, message.Text)
 
-	// 📩 Pass the event to EventBus
-	b.EventBus.Publish(core.DMMessageEvent, &core.BusMessage{
-		ReceiverPublicKey: event.PubKey,
+	return core.DMMessageEvent, &core.BusMessage{
+		EventID:           event.ID,
+		SenderPublicKey:   event.PubKey,
+		ReceiverPublicKey: event.PubKey, // Replies go back to the sender
 		Payload:           message,
-	})
+		Timestamp:         int64(event.CreatedAt),
+	}, nil
 }
 
 func (listener *DMListener) Filters(b *bot.BaseBot) []nostr.Filter {
@@ -98,13 +93,12 @@ func (listener *DMListener) Filters(b *bot.BaseBot) []nostr.Filter {
 		{
 			Kinds: []int{nostr.KindEncryptedDirectMessage},
 			Tags:  tags,
-			Limit: 50,
+			Limit: listener.Options.Limit,
 		},
 	}
 }
 
-// HandleConnectionLoss handles relay disconnections
+// HandleConnectionLoss handles relay disconnections. Reconnecting is left to the bot's supervisor
 func (listener *DMListener) HandleConnectionLoss(bot *bot.BaseBot) {
-	log.Println("🔄 Reconnecting DM Listener...")
-	bot.Start() // Attempt to restart the bot
+	log.Printf("🔄 [%s] DM Listener lost its relays", bot.Config.Name)
 }
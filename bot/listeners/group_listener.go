@@ -4,83 +4,82 @@ import (
 	"agent/bot"
 	"agent/core"
 	"encoding/json"
+	"fmt"
 	"log"
 
 	"github.com/nbd-wtf/go-nostr"
 )
 
+// GroupListenerOptions are the typed `listener_options` of a GroupListener
+type GroupListenerOptions struct {
+	Limit int `yaml:"limit"`
+}
+
 // GroupListener handles group channel events
 type GroupListener struct {
 	ChannelID string
+	Options   GroupListenerOptions
 }
 
-// StartListening subscribes to group channel events
-func (listener *GroupListener) StartListening(b *bot.BaseBot) {
-	relay := b.Relay
-	filters := listener.Filters(b)
+func init() {
+	bot.RegisterListener("GroupListener", func(config core.BotConfig, options core.ComponentOptions) (bot.EventListener, error) {
+		if config.ChannelID == "" {
+			return nil, fmt.Errorf("GroupListener needs a channel_id")
+		}
+		listener := &GroupListener{
+			ChannelID: config.ChannelID,
+			Options:   GroupListenerOptions{Limit: 100},
+		}
+		if err := options.Decode(&listener.Options); err != nil {
+			return nil, err
+		}
+		return listener, nil
+	})
+}
 
-	sub, err := relay.Subscribe(b.Context, filters)
-	if err != nil {
-		log.Printf("❌ Group subscription failed: %v", err)
-		return
+// StartListening subscribes to group channel events, catching up from the stored cursor
+func (listener *GroupListener) StartListening(b *bot.BaseBot) error {
+	sub := &subscription{
+		name:     "group:" + listener.ChannelID,
+		filters:  listener.Filters(b),
+		decode:   listener.decode,
+		listener: listener,
 	}
-	defer sub.Unsub()
-
-	var storedEvents []*nostr.Event
-	processingStoredEvents := false
-
-	for {
-		select {
-		case event, ok := <-sub.Events:
-			if !ok {
-				log.Println("🚫 Subscription closed, reconnecting...")
-				b.Relay.Close()
-				// return fmt.Errorf("subscription closed")
-			}
+	return sub.run(b)
+}
 
-			if !processingStoredEvents {
-				storedEvents = append(storedEvents, event)
-			} else if b.IsActiveListener {
-				listener.ProcessEvent(b, event)
-			}
+// ProcessEvent handles group channel messages
+func (listener *GroupListener) ProcessEvent(b *bot.BaseBot, event *nostr.Event) {
+	if !accept(b, event) {
+		return
+	}
 
-		case <-sub.EndOfStoredEvents:
-			if !processingStoredEvents {
-				log.Println("📥 Processing pending events...")
-				for i := len(storedEvents) - 1; i >= 0; i-- {
-					// bot.handleEvent(storedEvents[i])
-				}
-				storedEvents = nil
-				processingStoredEvents = true
-				b.IsActiveListener = true
-				log.Printf("👂 [%s] listening ", b.Config.Name)
-			}
-		case <-relay.Context().Done():
-			listener.HandleConnectionLoss(b)
-			return
-		}
+	eventType, message, err := listener.decode(b, event)
+	if err != nil {
+		log.Printf("%v", err)
+		return
 	}
+
+	b.EventBus.Publish(eventType, message)
 }
 
-// ProcessEvent handles group channel messages
-func (listener *GroupListener) ProcessEvent(b *bot.BaseBot, event *nostr.Event) {
+// decode turns a channel message into a bus message
+func (listener *GroupListener) decode(b *bot.BaseBot, event *nostr.Event) (core.EventType, *core.BusMessage, error) {
 	var message core.ContentStructure
 	if err := json.Unmarshal([]byte(event.Content), &message); err != nil {
-		log.Printf("Failed to unmarshal message: %v", err)
-		return
+		return "", nil, fmt.Errorf("failed to unmarshal message: %w", err)
 	}
 
-	b.EventBus.Publish(core.GroupMessageEvent, &core.BusMessage{
+	log.Printf("[%s] 👂 [%s]: %s", b.Config.Name, core.ShortID(listener.ChannelID), message)
+
+	return core.GroupMessageEvent, &core.BusMessage{
+		EventID:           event.ID,
 		ChannelID:         listener.ChannelID,
 		ReceiverPublicKey: b.PublicKey,
 		SenderPublicKey:   event.PubKey,
 		Payload:           message,
-	})
-
-	channelID := listener.ChannelID
-	channelID = channelID[len(channelID)-3:]
-
-	log.Printf("[%s] 👂 [%s]: %s", b.Config.Name, channelID, message)
+		Timestamp:         int64(event.CreatedAt),
+	}, nil
 }
 
 func (listener *GroupListener) Filters(b *bot.BaseBot) []nostr.Filter {
@@ -88,13 +87,12 @@ func (listener *GroupListener) Filters(b *bot.BaseBot) []nostr.Filter {
 		{
 			Kinds: []int{nostr.KindChannelMessage},
 			Tags:  map[string][]string{"e": {listener.ChannelID}},
-			Limit: 100,
+			Limit: listener.Options.Limit,
 		},
 	}
 }
 
-// HandleConnectionLoss reconnects the bot
+// HandleConnectionLoss handles relay disconnections. Reconnecting is left to the bot's supervisor
 func (listener *GroupListener) HandleConnectionLoss(bot *bot.BaseBot) {
-	log.Println("🔄 Reconnecting Group Listener...")
-	bot.Start()
+	log.Printf("🔄 [%s] Group Listener lost its relays", bot.Config.Name)
 }
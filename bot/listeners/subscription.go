@@ -0,0 +1,201 @@
+package listeners
+
+import (
+	"agent/bot"
+	"agent/core"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const (
+	cursorFlushEvery    = 100             // Events processed between cursor writes
+	cursorFlushInterval = 5 * time.Second // Longest a processed event stays unsaved
+)
+
+// decodeFunc turns a relay event into the message published on the EventBus
+type decodeFunc func(b *bot.BaseBot, event *nostr.Event) (core.EventType, *core.BusMessage, error)
+
+// subscription is the listen loop shared by every listener: it subscribes
+// from the stored cursor, replays the backlog in order once the relays
+// send EOSE and then processes live events, advancing the cursor as it goes.
+// The cursor is written out in batches, after the backlog and when the loop ends
+type subscription struct {
+	name     string // Cursor key
+	filters  []nostr.Filter
+	decode   decodeFunc
+	listener bot.EventListener
+}
+
+func (s *subscription) run(b *bot.BaseBot) error {
+	cursor, found, err := b.Cursors.Load(s.name)
+	if err != nil {
+		log.Printf("⚠️ [%s] Could not load cursor for %s: %v", b.Config.Name, s.name, err)
+	}
+
+	sub, err := b.Pool.Subscribe(b.ListenContext, s.since(b, cursor, found))
+	if err != nil {
+		return err
+	}
+	defer sub.Unsub()
+
+	writer := &cursorWriter{bot: b, name: s.name, cursor: cursor}
+	defer writer.flush()
+
+	ticker := time.NewTicker(cursorFlushInterval)
+	defer ticker.Stop()
+
+	var storedEvents []*nostr.Event
+	processingStoredEvents := false
+
+	for {
+		select {
+		case event, ok := <-sub.Events:
+			if !ok {
+				log.Println("🚫 Subscription closed, reconnecting...")
+				s.listener.HandleConnectionLoss(b)
+				return fmt.Errorf("subscription closed")
+			}
+
+			if !processingStoredEvents {
+				storedEvents = append(storedEvents, event)
+			} else if b.IsActiveListener.Load() {
+				s.process(b, event, writer, false)
+			}
+
+		case <-sub.EndOfStoredEvents:
+			if !processingStoredEvents {
+				s.replay(b, storedEvents, writer, found)
+				writer.flush()
+				storedEvents = nil
+				processingStoredEvents = true
+				b.IsActiveListener.Store(true)
+				log.Printf("👂 [%s] listening ", b.Config.Name)
+			}
+
+		case <-ticker.C:
+			writer.flush()
+
+		case <-sub.Done:
+			log.Printf("🚫 [%s] lost every read relay", b.Config.Name)
+			s.listener.HandleConnectionLoss(b)
+			return fmt.Errorf("all read relays lost")
+
+		case <-b.ListenContext.Done():
+			return nil
+		}
+	}
+}
+
+// since limits the filters to what happened after the cursor, bounded by the
+// catch-up window. Without a cursor only new events are requested
+func (s *subscription) since(b *bot.BaseBot, cursor bot.Cursor, found bool) nostr.Filters {
+	now := nostr.Now()
+	since := now
+
+	if found {
+		floor := now - nostr.Timestamp(b.Config.Cursor.MaxCatchUpDuration()/time.Second)
+		since = max(cursor.CreatedAt, floor)
+	}
+
+	filters := make(nostr.Filters, len(s.filters))
+	for i, filter := range s.filters {
+		filter.Since = &since
+		if found {
+			filter.Limit = 0 // Let the relay return the whole backlog
+		}
+		filters[i] = filter
+	}
+	return filters
+}
+
+// replay processes the stored events oldest first, skipping those already handled
+func (s *subscription) replay(b *bot.BaseBot, events []*nostr.Event, writer *cursorWriter, found bool) {
+	if !found || len(events) == 0 {
+		return
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].CreatedAt != events[j].CreatedAt {
+			return events[i].CreatedAt < events[j].CreatedAt
+		}
+		return events[i].ID < events[j].ID
+	})
+
+	log.Printf("📥 [%s] Catching up on %d events...", b.Config.Name, len(events))
+	for _, event := range events {
+		if writer.cursor.Before(event) {
+			s.process(b, event, writer, true)
+		}
+	}
+}
+
+// process publishes the event and moves the cursor past it
+func (s *subscription) process(b *bot.BaseBot, event *nostr.Event, writer *cursorWriter, ordered bool) {
+	if !accept(b, event) {
+		return
+	}
+
+	eventType, message, err := s.decode(b, event)
+	if err != nil {
+		log.Printf("❌ [%s] %v", b.Config.Name, err)
+	} else if ordered {
+		b.EventBus.PublishSync(eventType, message)
+	} else {
+		b.EventBus.Publish(eventType, message)
+	}
+
+	writer.advance(event)
+}
+
+// cursorWriter holds a subscription's cursor in memory and saves it every
+// cursorFlushEvery events, so a busy channel does not rewrite the cursor
+// file for each event. A crash loses at most that batch, which the
+// deduplicator drops when it is replayed
+type cursorWriter struct {
+	bot     *bot.BaseBot
+	name    string
+	cursor  bot.Cursor
+	unsaved int // Events processed since the last save
+}
+
+// advance moves the cursor past the event, saving it once enough events piled up
+func (w *cursorWriter) advance(event *nostr.Event) {
+	if event.CreatedAt < w.cursor.CreatedAt {
+		return
+	}
+	w.cursor = bot.Cursor{CreatedAt: event.CreatedAt, EventID: event.ID}
+	if w.unsaved++; w.unsaved >= cursorFlushEvery {
+		w.flush()
+	}
+}
+
+// flush saves the cursor if it moved since the last save
+func (w *cursorWriter) flush() {
+	if w.unsaved == 0 {
+		return
+	}
+	if err := w.bot.Cursors.Save(w.name, w.cursor); err != nil {
+		log.Printf("⚠️ [%s] Could not save cursor for %s: %v", w.bot.Config.Name, w.name, err)
+		return
+	}
+	w.unsaved = 0
+}
+
+// accept runs the event through the bot's deduplicator, logging why it was dropped
+func accept(b *bot.BaseBot, event *nostr.Event) bool {
+	err := b.Dedup.Check(event)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, bot.ErrDuplicateEvent):
+		// Expected with several relays or after a replay, not worth a log line
+	default:
+		log.Printf("🛡️ [%s] Dropped event %s: %v", b.Config.Name, event.ID, err)
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package listeners
+
+import (
+	"agent/bot"
+	"agent/core"
+	"fmt"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestCursorWriterSavesInBatches(t *testing.T) {
+	store := bot.NewMemoryCursorStore()
+	writer := &cursorWriter{bot: &bot.BaseBot{Config: core.BotConfig{Name: "test"}, Cursors: store}, name: "dm"}
+
+	event := func(i int) *nostr.Event {
+		return &nostr.Event{ID: fmt.Sprintf("e%d", i), CreatedAt: nostr.Timestamp(1000 + i)}
+	}
+	saved := func() bot.Cursor {
+		cursor, _, _ := store.Load("dm")
+		return cursor
+	}
+
+	for i := 1; i < cursorFlushEvery; i++ {
+		writer.advance(event(i))
+	}
+	if _, found, _ := store.Load("dm"); found {
+		t.Fatalf("cursor saved after %d events, want a batch of %d", cursorFlushEvery-1, cursorFlushEvery)
+	}
+
+	writer.advance(event(cursorFlushEvery))
+	if cursor := saved(); cursor.EventID != fmt.Sprintf("e%d", cursorFlushEvery) {
+		t.Fatalf("cursor = %+v after a full batch", cursor)
+	}
+
+	// An older event does not move the cursor back
+	writer.advance(event(1))
+	writer.advance(event(cursorFlushEvery + 1))
+	writer.flush()
+	if cursor := saved(); cursor.EventID != fmt.Sprintf("e%d", cursorFlushEvery+1) {
+		t.Fatalf("cursor = %+v after flush, want the newest event", cursor)
+	}
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Mailbox is a bot's actor loop: a bounded queue drained by a single
+// goroutine, so programs and their state are only ever touched in order
+type Mailbox struct {
+	queue   chan func()
+	pending inflight // Queued and running items plus scheduled timers
+	dropped atomic.Int64
+}
+
+func newMailbox(size int) *Mailbox {
+	return &Mailbox{queue: make(chan func(), size)}
+}
+
+// run processes queued items until the context ends
+func (m *Mailbox) run(ctx context.Context) {
+	for {
+		select {
+		case fn := <-m.queue:
+			fn()
+			m.pending.add(-1)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Post queues fn without waiting, dropping it when the mailbox is full
+func (m *Mailbox) Post(fn func()) bool {
+	m.pending.add(1)
+	select {
+	case m.queue <- fn:
+		return true
+	default:
+		m.pending.add(-1)
+		m.dropped.Add(1)
+		return false
+	}
+}
+
+// Call runs fn on the mailbox goroutine and waits for it to return. Unlike
+// Post it waits for room in the queue. It must not be called from the
+// mailbox, where it would wait on itself; mailbox items use the bot's
+// *Locked helpers instead
+func (m *Mailbox) Call(ctx context.Context, fn func()) error {
+	done := make(chan struct{})
+
+	m.pending.add(1)
+	select {
+	case m.queue <- func() { fn(); close(done) }:
+	case <-ctx.Done():
+		m.pending.add(-1)
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Dropped returns how many posts were rejected because the mailbox was full
+func (m *Mailbox) Dropped() int64 {
+	return m.dropped.Load()
+}
+
+// Drain waits for queued items and scheduled timers to finish, or for the context to end
+func (m *Mailbox) Drain(ctx context.Context) error {
+	if running, err := m.pending.wait(ctx); err != nil {
+		return fmt.Errorf("%d mailbox items still pending: %w", running, err)
+	}
+	return nil
+}
+
+// inflight counts running work and lets callers wait for it to reach zero,
+// including work started while they wait
+type inflight struct {
+	mu    sync.Mutex
+	count int
+	idle  chan struct{} // Closed when count drops to zero, nil when nobody waits
+}
+
+func (f *inflight) add(delta int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.count += delta
+	if f.count == 0 && f.idle != nil {
+		close(f.idle)
+		f.idle = nil
+	}
+}
+
+// wait blocks until the count is zero, returning what is still running when the context ends first
+func (f *inflight) wait(ctx context.Context) (int, error) {
+	f.mu.Lock()
+	if f.count == 0 {
+		f.mu.Unlock()
+		return 0, nil
+	}
+	if f.idle == nil {
+		f.idle = make(chan struct{})
+	}
+	idle := f.idle
+	f.mu.Unlock()
+
+	select {
+	case <-idle:
+		return 0, nil
+	case <-ctx.Done():
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.count, ctx.Err()
+	}
+}
@@ -0,0 +1,223 @@
+package bot
+
+import (
+	"agent/bot/programs"
+	"agent/core"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestBot builds a bot with just a mailbox and history, enough to run programs
+func newTestBot(t *testing.T, mailboxSize int) *BaseBot {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bot := &BaseBot{
+		Config:     core.BotConfig{Name: "test"},
+		Context:    ctx,
+		CancelFunc: cancel,
+		Mailbox:    newMailbox(mailboxSize),
+		History:    NewExecutionHistory(10),
+	}
+	go bot.Mailbox.run(ctx)
+	t.Cleanup(cancel)
+	return bot
+}
+
+// countingProgram counts its runs without any locking, so the race detector
+// catches runs the mailbox does not serialize
+type countingProgram struct {
+	runs int
+}
+
+func (p *countingProgram) ShouldRun(message *core.BusMessage) bool { return true }
+func (p *countingProgram) IsActive() bool                          { return true }
+
+func (p *countingProgram) Run(ctx context.Context, bot programs.Bot, message *core.BusMessage) programs.Result {
+	p.runs++
+	return programs.OK()
+}
+
+func drain(t *testing.T, mailbox *Mailbox) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := mailbox.Drain(ctx); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+}
+
+func TestMailboxRunsInOrder(t *testing.T) {
+	bot := newTestBot(t, 100)
+
+	var order []int
+	for i := 0; i < 50; i++ {
+		if !bot.Mailbox.Post(func() { order = append(order, i) }) {
+			t.Fatalf("post %d dropped", i)
+		}
+	}
+	drain(t, bot.Mailbox)
+
+	if len(order) != 50 {
+		t.Fatalf("ran %d items, want 50", len(order))
+	}
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("item %d ran as %d", i, got)
+		}
+	}
+}
+
+func TestMailboxDropsWhenFull(t *testing.T) {
+	bot := newTestBot(t, 1)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	bot.Mailbox.Post(func() { close(started); <-release })
+	<-started
+
+	if !bot.Mailbox.Post(func() {}) {
+		t.Fatal("post into the free slot dropped")
+	}
+	if bot.Mailbox.Post(func() {}) {
+		t.Fatal("post into a full mailbox accepted")
+	}
+	if dropped := bot.Mailbox.Dropped(); dropped != 1 {
+		t.Fatalf("dropped = %d, want 1", dropped)
+	}
+
+	close(release)
+	drain(t, bot.Mailbox)
+}
+
+func TestMailboxDrainWaitsForItems(t *testing.T) {
+	bot := newTestBot(t, 10)
+
+	var finished atomic.Bool
+	bot.Mailbox.Post(func() {
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+	})
+	drain(t, bot.Mailbox)
+
+	if !finished.Load() {
+		t.Fatal("drain returned before the item finished")
+	}
+}
+
+func TestMailboxDrainTimesOut(t *testing.T) {
+	bot := newTestBot(t, 10)
+
+	release := make(chan struct{})
+	defer close(release)
+	bot.Mailbox.Post(func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bot.Mailbox.Drain(ctx); err == nil {
+		t.Fatal("drain of a stuck item returned nil")
+	}
+}
+
+func TestMailboxCallWaitsForResult(t *testing.T) {
+	bot := newTestBot(t, 10)
+
+	value := 0
+	if err := bot.Mailbox.Call(context.Background(), func() { value = 42 }); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if value != 42 {
+		t.Fatalf("value = %d after call, want 42", value)
+	}
+}
+
+func TestRemoveProgramFromMailbox(t *testing.T) {
+	bot := newTestBot(t, 10)
+
+	first, second := &countingProgram{}, &countingProgram{}
+	bot.AssignPrograms([]programs.BotProgram{first, second})
+
+	// A mailbox item changes the programs through the locked helpers, not Call
+	bot.Mailbox.Post(func() { bot.removeProgramLocked(first) })
+	drain(t, bot.Mailbox)
+
+	var remaining []programs.BotProgram
+	bot.Mailbox.Call(context.Background(), func() { remaining = append(remaining, bot.Programs...) })
+	if len(remaining) != 1 || remaining[0] != second {
+		t.Fatalf("programs = %v, want only the second", remaining)
+	}
+}
+
+func TestMailboxCallHonoursContext(t *testing.T) {
+	bot := newTestBot(t, 1)
+
+	release := make(chan struct{})
+	defer close(release)
+	bot.Mailbox.Post(func() { <-release })
+	bot.Mailbox.Post(func() {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bot.Mailbox.Call(ctx, func() {}); err == nil {
+		t.Fatal("call into a stuck mailbox returned nil")
+	}
+}
+
+func TestAfterRunsAndIsDrained(t *testing.T) {
+	bot := newTestBot(t, 10)
+
+	var ran atomic.Bool
+	bot.After(30*time.Millisecond, func() { ran.Store(true) })
+	drain(t, bot.Mailbox)
+
+	if !ran.Load() {
+		t.Fatal("drain returned before the timer fired")
+	}
+}
+
+func TestAfterSkippedOnStop(t *testing.T) {
+	bot := newTestBot(t, 10)
+
+	var ran atomic.Bool
+	bot.After(time.Hour, func() { ran.Store(true) })
+	bot.CancelFunc()
+	drain(t, bot.Mailbox)
+
+	if ran.Load() {
+		t.Fatal("timer fired after the bot stopped")
+	}
+}
+
+func TestExecuteProgramsConcurrently(t *testing.T) {
+	bot := newTestBot(t, 1000)
+
+	program := &countingProgram{}
+	bot.AssignPrograms([]programs.BotProgram{program})
+
+	const senders, messages = 8, 50
+	var wg sync.WaitGroup
+	for s := 0; s < senders; s++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < messages; i++ {
+				bot.ExecutePrograms(&core.BusMessage{SenderPublicKey: string(rune('a' + s))})
+			}
+		}()
+	}
+	wg.Wait()
+	drain(t, bot.Mailbox)
+
+	var runs int
+	bot.Mailbox.Call(context.Background(), func() { runs = program.runs })
+	if runs != senders*messages {
+		t.Fatalf("runs = %d, want %d", runs, senders*messages)
+	}
+	if counts := bot.History.Counts(); counts[programs.StatusOK] != uint64(senders*messages) {
+		t.Fatalf("history counted %d ok runs, want %d", counts[programs.StatusOK], senders*messages)
+	}
+}
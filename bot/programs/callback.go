@@ -3,6 +3,7 @@ package programs
 import (
 	"agent/core"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -14,17 +15,18 @@ import (
 
 // **CallbackProgram** - Handles responding when mentioned
 type CallbackProgram struct {
-	IsRunning       bool
-	CurrentRunCount int
+	Runner
 
 	ProgramConfig core.ProgramConfig
-
-	Peers []string
 }
 
-// ✅ **Check if the program is active**
-func (p *CallbackProgram) IsActive() bool {
-	return p.IsRunning
+func init() {
+	Register("CallbackProgram", func(config core.ProgramConfig, peers []string) (BotProgram, error) {
+		return &CallbackProgram{
+			ProgramConfig: config,
+			Runner:        Runner{Peers: peers},
+		}, nil
+	})
 }
 
 // ✅ **Should this program run?**
@@ -33,13 +35,13 @@ func (p *CallbackProgram) ShouldRun(message *core.BusMessage) bool {
 }
 
 // ✅ **Run Callback Logic**
-func (p *CallbackProgram) Run(bot Bot, message *core.BusMessage) string {
+func (p *CallbackProgram) Run(ctx context.Context, bot Bot, message *core.BusMessage) Result {
 	log.Printf("🏃 [%s] [CallbackProgram] [%d]", bot.GetPublicKey(), p.CurrentRunCount)
 
 	if p.CurrentRunCount >= p.ProgramConfig.MaxRunCount {
 		log.Printf("🛑 [%s] [CallbackProgram] reached max run count. Terminating...", bot.GetPublicKey())
 		p.IsRunning = false
-		return "🔴"
+		return Finished("max run count reached")
 	}
 
 	if !p.IsRunning {
@@ -59,51 +61,52 @@ func (p *CallbackProgram) Run(bot Bot, message *core.BusMessage) string {
 	re, err := regexp.Compile(pattern)
 	if err != nil {
 		log.Printf("Error compiling regex: %v", err)
-		return "❌" // Indicate an error
+		return Failed(fmt.Errorf("invalid pattern: %w", err))
 	}
 
-	time.Sleep(time.Duration(p.ProgramConfig.ResponseDelay) * time.Second)
-
-	signal := "🟠"
+	matches := 0
 	if re.MatchString(text) {
 		log.Println("text matched")
 
 		log.Println(text)
-
-		data := PostData{
-			Message: message.Payload.Text,
-		}
-
-		postData(p.ProgramConfig.CallbackUrl, data)
-		signal = "🟢"
+		matches++
 	}
 
 	if re.MatchString(kind) {
 		log.Println("kind matched")
+		matches++
+	}
 
-		data := PostData{
-			Message: message.Payload.Text,
-		}
+	if matches == 0 {
+		return Skipped("no match")
+	}
 
-		postData(p.ProgramConfig.CallbackUrl, data)
-		signal = "🟢"
+	data := PostData{
+		Message: message.Payload.Text,
 	}
 
-	return signal
+	// One callback per match, after the response delay
+	bot.After(time.Duration(p.ProgramConfig.ResponseDelay)*time.Second, func() {
+		for i := 0; i < matches; i++ {
+			postData(ctx, p.ProgramConfig.CallbackUrl, data)
+		}
+	})
+
+	return OK()
 }
 
 type PostData struct {
 	Message string `json:"message"`
 }
 
-func postData(url string, data PostData) {
+func postData(ctx context.Context, url string, data PostData) {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		fmt.Println("Error marshalling JSON:", err)
 		return
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		fmt.Println("Error creating request:", err)
 		return
@@ -2,6 +2,8 @@ package programs
 
 import (
 	"agent/core"
+	"context"
+	"fmt"
 	"log"
 	"strings"
 	"time"
@@ -11,19 +13,21 @@ import (
 
 // **ChatterProgram** - Handles starting and responding
 type ChatterProgram struct {
-	IsRunning       bool
-	CurrentRunCount int
+	Runner
 
 	ProgramConfig core.ProgramConfig
 
-	Peers []string
-
 	Leader bool
 }
 
-// ✅ **Check if the program is active**
-func (p *ChatterProgram) IsActive() bool {
-	return p.IsRunning
+func init() {
+	Register("ChatterProgram", func(config core.ProgramConfig, peers []string) (BotProgram, error) {
+		return &ChatterProgram{
+			ProgramConfig: config,
+			Leader:        config.Leader,
+			Runner:        Runner{Peers: peers},
+		}, nil
+	})
 }
 
 // ✅ **Determine if this should run**
@@ -33,13 +37,13 @@ func (p *ChatterProgram) ShouldRun(message *core.BusMessage) bool {
 }
 
 // ✅ **Run Chatter Logic**
-func (p *ChatterProgram) Run(bot Bot, message *core.BusMessage) string {
+func (p *ChatterProgram) Run(ctx context.Context, bot Bot, message *core.BusMessage) Result {
 	log.Printf("🏃 [%s] [ChatterProgram] [%d]", bot.GetPublicKey(), p.CurrentRunCount)
 
 	if p.CurrentRunCount >= p.ProgramConfig.MaxRunCount {
 		log.Printf("🛑 [%s] [ChatterProgram] reached max run count. Terminating...", bot.GetPublicKey())
 		p.IsRunning = false
-		return "🔴"
+		return Finished("max run count reached")
 	}
 
 	if !p.IsRunning {
@@ -47,25 +51,22 @@ func (p *ChatterProgram) Run(bot Bot, message *core.BusMessage) string {
 		p.CurrentRunCount = 0
 	}
 
-	time.Sleep(time.Duration(p.CurrentRunCount) * time.Second)
+	// Each round waits longer: CurrentRunCount seconds before counting it and again after
+	delay := time.Duration(2*p.CurrentRunCount+1) * time.Second
 
 	p.CurrentRunCount++
 
-	p.startToMention(bot, message)
-
-	return "🟢"
+	return p.startToMention(bot, message, delay)
 }
 
-// ✅ **Mention the next bot**
-func (p *ChatterProgram) startToMention(bot Bot, message *core.BusMessage) {
-	time.Sleep(time.Duration(p.CurrentRunCount) * time.Second)
-
+// ✅ **Mention the next bot after the delay**
+func (p *ChatterProgram) startToMention(bot Bot, message *core.BusMessage, delay time.Duration) Result {
 	receiver := bot.GetNextReceiver(p)
 
 	encodedPublicKey, err := nip19.EncodePublicKey(receiver)
 	if err != nil {
 		log.Printf("❌ Error encoding public key: %v", err)
-		return
+		return Failed(fmt.Errorf("could not encode receiver: %w", err))
 	}
 
 	reply := &core.BusMessage{
@@ -78,5 +79,6 @@ func (p *ChatterProgram) startToMention(bot Bot, message *core.BusMessage) {
 		},
 	}
 
-	bot.Publish(reply)
+	bot.After(delay, func() { bot.Publish(reply) })
+	return OK(reply)
 }
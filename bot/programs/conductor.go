@@ -2,32 +2,270 @@ package programs
 
 import (
 	"agent/core"
-	"bytes"
+	grpcclient "agent/services/grpc"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	pb "github.com/prorobot-ai/grpc-protos/gen/crawler"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	statusTimeout  = 15 * time.Second // How long a status query may stream
+	maxStatusSteps = 5                // Status changes kept in a status reply
 )
 
 // ConductorProgram handles responses when mentioned
 type ConductorProgram struct {
-	IsRunning       bool
-	CurrentRunCount int
-	ProgramConfig   core.ProgramConfig
-	Peers           []string
-	CrawlerClient   pb.CrawlerServiceClient
+	Runner
+	ProgramConfig core.ProgramConfig
+	Crawler       *grpcclient.CrawlerClient // Default crawler, given a bare URL; nil when only workers are configured
+
+	routes    map[string]*workerRoute // Command verbs handed to worker backends
+	jobs      *JobManager
+	ledger    *JobLedger      // Jobs kept across restarts, saved with the program state
+	ctx       context.Context // Ends when the bot or the program stops, aborting running jobs
+	cancel    context.CancelFunc
+	mu        sync.Mutex
+	notifiers map[core.Notifier]struct{} // Hub connections of running jobs
+	operators map[string]bool            // Hex public keys that may query any crawler job ID
+	stopOnce  sync.Once
+	stopErr   error
+
+	stateMu sync.Mutex // Serializes saves from the mailbox and from job workers
+	state   State
+	saved   RunState // Run state as of the last SaveState, for saves from job workers
+}
+
+// conductorState is what a ConductorProgram persists between restarts
+type conductorState struct {
+	RunState
+	Jobs []LedgerEntry `json:"jobs,omitempty"`
+}
+
+func init() {
+	Register("ConductorProgram", func(config core.ProgramConfig, peers []string) (BotProgram, error) {
+		operators := make(map[string]bool)
+		for _, operator := range config.Operators {
+			if key, ok := core.HexPublicKey(operator); ok {
+				operators[key] = true
+			}
+		}
+		return &ConductorProgram{
+			ProgramConfig: config,
+			Runner:        Runner{Peers: peers},
+			notifiers:     make(map[core.Notifier]struct{}),
+			operators:     operators,
+		}, nil
+	})
+}
+
+// ✅ **Connect to the workers and start the job workers before the first run**
+func (p *ConductorProgram) Init(ctx context.Context, bot Bot) error {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+
+	// A bare URL goes to the default crawler, kept for configs without a worker registry
+	if p.ProgramConfig.WorkerConfig.Address != "" || len(p.ProgramConfig.Workers) == 0 {
+		if err := p.InitCrawlerClient(p.ProgramConfig.WorkerConfig); err != nil {
+			return err
+		}
+	}
+
+	if err := p.initRoutes(); err != nil {
+		p.closeWorkers()
+		return err
+	}
+
+	if p.ledger == nil {
+		p.ledger = NewJobLedger(nil)
+	}
+
+	worker := p.ProgramConfig.WorkerConfig
+	p.jobs = NewJobManager(p.ctx, worker.Workers(), worker.QueueCapacity(), worker.JobDeadline(),
+		func(ctx context.Context, job *Job) error { return p.runJob(ctx, bot, job) },
+		func(job *Job) { p.jobFinished(bot, job) },
+	)
+
+	p.recoverJobs(bot)
+	return nil
+}
+
+// initRoutes builds the worker backends of the configured command verbs
+func (p *ConductorProgram) initRoutes() error {
+	p.routes = make(map[string]*workerRoute)
+	for verb, config := range p.ProgramConfig.Workers {
+		route, err := newWorkerRoute(verb, config)
+		if err != nil {
+			return fmt.Errorf("worker %q: %w", verb, err)
+		}
+		p.routes[verb] = route
+	}
+	return nil
+}
+
+// recoverJobs queues the jobs that were unfinished when the bot last stopped
+func (p *ConductorProgram) recoverJobs(bot Bot) {
+	last := 0
+	for _, entry := range p.ledger.Entries() {
+		if id, err := strconv.Atoi(entry.ID); err == nil && id > last {
+			last = id
+		}
+	}
+	p.jobs.ContinueIDs(last)
+
+	for _, entry := range p.ledger.Unfinished() {
+		route := p.routes[entry.Job.Verb]
+		job := &Job{
+			ID:        entry.ID,
+			User:      entry.User,
+			ChannelID: entry.Job.ChannelID,
+			SessionID: entry.Job.SessionID,
+			Payload:   entry.Job.Payload,
+			Verb:      entry.Job.Verb,
+		}
+
+		switch {
+		case entry.Job.Verb != "" && route == nil:
+			log.Printf("⚠️ [%s] [ConductorProgram] Could not recover job #%s: no worker for %q", bot.GetName(), entry.ID, entry.Job.Verb)
+			p.recoveryFailed(bot, job, fmt.Errorf("no worker for %q", entry.Job.Verb))
+			continue
+		case route != nil && route.backend != nil && entry.State == JobRunning:
+			// Only crawls can be reattached, running other jobs again could repeat their effects
+			log.Printf("⚠️ [%s] [ConductorProgram] Job #%s was interrupted by a restart", bot.GetName(), entry.ID)
+			p.recoveryFailed(bot, job, errors.New("interrupted by a restart"))
+			continue
+		}
+
+		if route != nil {
+			job.Timeout = route.config.Deadline(p.ProgramConfig.WorkerConfig)
+		}
+		if _, err := p.jobs.Submit(job); err != nil {
+			log.Printf("⚠️ [%s] [ConductorProgram] Could not recover job #%s: %v", bot.GetName(), entry.ID, err)
+			p.recoveryFailed(bot, job, err)
+			continue
+		}
+		log.Printf("🔁 [%s] [ConductorProgram] Recovered %s job #%s for %s", bot.GetName(), entry.State, entry.ID, entry.Job.Payload)
+	}
+	p.persist()
+}
+
+// recoveryFailed fails a job that could not be recovered and tells its user
+func (p *ConductorProgram) recoveryFailed(bot Bot, job *Job, err error) {
+	job.State, job.Err = JobFailed, err
+	p.ledger.Transition(job.ID, JobFailed, err)
+	p.replyFailed(bot, job)
+}
+
+// runJob runs a worker command, starts a crawl, or reattaches to a crawl
+// that was running before a restart
+func (p *ConductorProgram) runJob(ctx context.Context, bot Bot, job *Job) error {
+	remoteJob := core.RemoteJob{
+		ChannelID: job.ChannelID,
+		SessionID: job.SessionID,
+		Payload:   job.Payload,
+		Verb:      job.Verb,
+	}
+
+	if route := p.routes[job.Verb]; route != nil && route.backend != nil {
+		p.ledger.Start(job.ID, job.User, remoteJob, job.Queued)
+		p.persist()
+		return p.runBackend(ctx, bot, route, job.ID, remoteJob)
+	}
+	progress := func(workerJobID, message string) {
+		if p.ledger.Progress(job.ID, workerJobID, message) {
+			p.persist()
+		}
+	}
+
+	if entry, found := p.ledger.Get(job.ID); found && entry.State == JobRunning && entry.WorkerJobID != "" {
+		log.Printf("🔁 [%s] [ConductorProgram] Reattaching job #%s to worker job [%s]", bot.GetName(), job.ID, entry.WorkerJobID)
+		return p.ResumeWorkerJob(ctx, bot, remoteJob, entry.WorkerJobID, progress)
+	}
+
+	p.ledger.Start(job.ID, job.User, remoteJob, job.Queued)
+	p.persist()
+	return p.StartWorkerJob(ctx, bot, remoteJob, progress)
+}
+
+// runBackend runs a worker command and replies with its formatted output
+func (p *ConductorProgram) runBackend(ctx context.Context, bot Bot, route *workerRoute, id string, remoteJob core.RemoteJob) error {
+	args, err := core.BindArgs(route.args, strings.Fields(remoteJob.Payload))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("🛠️ [%s] [ConductorProgram] Job #%s runs %s %v", bot.GetName(), id, route.verb, args)
+	output, err := route.backend.Run(ctx, args)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	text, err := route.format(id, args, output)
+	if err != nil {
+		return err
+	}
+	if p.ledger.Progress(id, "", snippet([]byte(output))) {
+		p.persist()
+	}
+
+	p.reply(bot, &core.BusMessage{ChannelID: remoteJob.ChannelID, Payload: core.ContentStructure{Metadata: remoteJob.SessionID}}, text)
+	return nil
+}
+
+// ✅ **Restore the run count, running flag and job ledger saved before a restart**
+func (p *ConductorProgram) LoadState(state State) error {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+
+	p.state = state
+
+	var saved conductorState
+	found, err := state.Load(&saved)
+	if found {
+		p.IsRunning, p.CurrentRunCount = saved.IsRunning, saved.CurrentRunCount
+		p.saved = saved.RunState
+	}
+	p.ledger = NewJobLedger(saved.Jobs)
+	return err
+}
+
+// ✅ **Save the run count, running flag and job ledger**
+func (p *ConductorProgram) SaveState(state State) error {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+
+	p.saved = RunState{IsRunning: p.IsRunning, CurrentRunCount: p.CurrentRunCount}
+	return p.save(state)
+}
+
+// persist saves the ledger after a job changed, from outside the mailbox
+func (p *ConductorProgram) persist() {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+
+	if p.state == nil {
+		return
+	}
+	if err := p.save(p.state); err != nil {
+		log.Printf("⚠️ [ConductorProgram] could not save job ledger: %v", err)
+	}
 }
 
-// ✅ **Check if the program is active**
-func (p *ConductorProgram) IsActive() bool {
-	return p.IsRunning
+func (p *ConductorProgram) save(state State) error {
+	var jobs []LedgerEntry
+	if p.ledger != nil {
+		jobs = p.ledger.Entries()
+	}
+	return state.Save(conductorState{RunState: p.saved, Jobs: jobs})
 }
 
 // ✅ **Should this program run?**
@@ -36,13 +274,13 @@ func (p *ConductorProgram) ShouldRun(message *core.BusMessage) bool {
 }
 
 // ✅ **Run Responder Logic**
-func (p *ConductorProgram) Run(bot Bot, message *core.BusMessage) string {
+func (p *ConductorProgram) Run(ctx context.Context, bot Bot, message *core.BusMessage) Result {
 	log.Printf("🏃 [%s] [ConductorProgram] [%d]", bot.GetPublicKey(), p.CurrentRunCount)
 
 	if p.CurrentRunCount >= p.ProgramConfig.MaxRunCount {
 		log.Printf("🛑 [%s] [ConductorProgram] reached max run count. Terminating...", bot.GetPublicKey())
 		p.IsRunning = false
-		return "🔴"
+		return Finished("max run count reached")
 	}
 
 	if !p.IsRunning {
@@ -58,104 +296,271 @@ func (p *ConductorProgram) Run(bot Bot, message *core.BusMessage) string {
 	set := createSet(aliases)
 
 	if mention == "" || !set[mention] {
-		return "🟠 No valid mention"
+		return Skipped("not addressed to this bot")
 	}
 
 	words := core.SplitMessageContent(text)
 	if len(words) < 2 {
 		log.Println("⚠️ Malformed message, missing number.")
-		return "🟠"
+		return Skipped("malformed message, missing payload")
 	}
 
-	time.Sleep(time.Duration(p.ProgramConfig.ResponseDelay) * time.Second)
+	if p.jobs == nil {
+		return Failed(fmt.Errorf("job manager is not initialized"))
+	}
 
-	remoteJob := &core.RemoteJob{
+	switch words[1] {
+	case "jobs":
+		return p.reply(bot, message, p.listJobs())
+	case "queue":
+		return p.reply(bot, message, p.listQueue())
+	case "cancel":
+		if len(words) < 3 {
+			return p.reply(bot, message, "⚠️ Usage: cancel <job>")
+		}
+		return p.reply(bot, message, p.cancelJob(strings.TrimPrefix(words[2], "#"), message.SenderPublicKey))
+	case "status":
+		if len(words) < 3 {
+			return p.reply(bot, message, "⚠️ Usage: status <job>")
+		}
+		p.queryJobStatus(bot, message, words[2])
+		return OK()
+	}
+
+	if route, found := p.routes[words[1]]; found {
+		fields := strings.Fields(strings.Join(words[2:], " "))
+		if _, err := core.BindArgs(route.args, fields); err != nil {
+			return p.reply(bot, message, fmt.Sprintf("⚠️ %v. Usage: @%s %s", err, mention, route.usage()))
+		}
+
+		job := &Job{
+			User:      message.SenderPublicKey,
+			ChannelID: message.ChannelID,
+			SessionID: message.Payload.Metadata,
+			Payload:   strings.Join(fields, " "),
+			Verb:      route.verb,
+			Timeout:   route.config.Deadline(p.ProgramConfig.WorkerConfig),
+		}
+		return p.submit(bot, message, job, route.crawler)
+	}
+
+	if p.Crawler == nil {
+		return p.reply(bot, message, fmt.Sprintf("🤷 Unknown command %q. Try: %s", words[1], strings.Join(p.commands(), ", ")))
+	}
+
+	// The crawl streams for a while, the job manager keeps it off the bot's mailbox
+	return p.submit(bot, message, &Job{
+		User:      message.SenderPublicKey,
 		ChannelID: message.ChannelID,
 		SessionID: message.Payload.Metadata,
 		Payload:   words[1],
+	}, p.Crawler)
+}
+
+// submit queues a job, failing fast while the circuit breaker keeps its crawler off
+func (p *ConductorProgram) submit(bot Bot, message *core.BusMessage, job *Job, crawler *grpcclient.CrawlerClient) Result {
+	if crawler != nil && !crawler.Available() {
+		return p.reply(bot, message, "🚧 Crawler unavailable, please try again in a moment.")
 	}
 
-	p.StartWorkerJob(bot, *remoteJob)
+	position, err := p.jobs.Submit(job)
+	if err != nil {
+		log.Printf("⚠️ [%s] [ConductorProgram] Could not queue job: %v", bot.GetName(), err)
+		return p.reply(bot, message, fmt.Sprintf("⚠️ Could not queue %s: %v", job.Payload, err))
+	}
+	p.ledger.Add(job.ID, job.User, core.RemoteJob{ChannelID: job.ChannelID, SessionID: job.SessionID, Payload: job.Payload, Verb: job.Verb}, job.Queued)
+	p.persist()
 
-	return "🟢"
+	log.Printf("🧾 [%s] [ConductorProgram] Job #%s for %s (queue position %d)", bot.GetName(), job.ID, job.Payload, position)
+	if position > 0 {
+		return p.reply(bot, message, fmt.Sprintf("⏳ Job #%s queued at position %d.", job.ID, position))
+	}
+	return OK()
 }
 
-// ✅ **Initialize gRPC Client in the Program**
-func (p *ConductorProgram) InitCrawlerClient(serverAddr string) {
-	opts := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+// commands lists what the bot answers to, its own commands first
+func (p *ConductorProgram) commands() []string {
+	verbs := make([]string, 0, len(p.routes))
+	for verb := range p.routes {
+		verbs = append(verbs, p.routes[verb].usage())
 	}
+	sort.Strings(verbs)
+	return append(append([]string{}, core.ReservedWorkerVerbs...), verbs...)
+}
 
-	conn, err := grpc.NewClient(serverAddr, opts...)
+// ✅ **Reply to a command in the channel it came from**
+func (p *ConductorProgram) reply(bot Bot, message *core.BusMessage, text string) Result {
+	reply := &core.BusMessage{
+		ChannelID:         message.ChannelID,
+		ReceiverPublicKey: bot.GetPublicKey(),
+		Payload: core.ContentStructure{
+			Kind:     "message",
+			Metadata: message.Payload.Metadata,
+			Text:     core.SerializeContent(text, "message"),
+		},
+	}
+	bot.Publish(reply)
+	return OK(reply)
+}
+
+func (p *ConductorProgram) listJobs() string {
+	jobs := p.jobs.Running()
+	if len(jobs) == 0 {
+		return "🧾 No jobs."
+	}
+
+	lines := []string{"🧾 Jobs:"}
+	for _, job := range jobs {
+		line := fmt.Sprintf("#%s %s %s", job.ID, job.State, strings.TrimSpace(job.Verb+" "+job.Payload))
+		switch {
+		case job.State == JobRunning:
+			line += fmt.Sprintf(" (%s)", time.Since(job.Started).Round(time.Second))
+		case job.Err != nil:
+			line += fmt.Sprintf(" (%v)", job.Err)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p *ConductorProgram) listQueue() string {
+	jobs := p.jobs.Queue()
+	if len(jobs) == 0 {
+		return "⏳ Queue is empty."
+	}
+
+	lines := []string{"⏳ Queue:"}
+	for i, job := range jobs {
+		lines = append(lines, fmt.Sprintf("%d. #%s %s (waiting %s)", i+1, job.ID, strings.TrimSpace(job.Verb+" "+job.Payload), time.Since(job.Queued).Round(time.Second)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p *ConductorProgram) cancelJob(id, user string) string {
+	job, err := p.jobs.Cancel(id, user)
 	if err != nil {
-		log.Fatalf("❌ Failed to connect to crawler service: %v", err)
+		return fmt.Sprintf("⚠️ Could not cancel job #%s: %v", id, err)
 	}
 
-	p.CrawlerClient = pb.NewCrawlerServiceClient(conn)
+	// Running jobs are recorded once their worker returns
+	if job.Started.IsZero() {
+		p.ledger.Transition(job.ID, JobCancelled, nil)
+		p.persist()
+	}
+	return fmt.Sprintf("🛑 Job #%s cancelled.", job.ID)
 }
 
-// ✅ **Send Crawl Request**
-func (p *ConductorProgram) StartWorkerJob(bot Bot, remoteJob core.RemoteJob) {
-	if p.CrawlerClient == nil {
-		log.Println("❌ Crawler Client is not initialized")
+// jobFinished reports jobs that did not finish on their own. Successful
+// crawls already replied with their report
+func (p *ConductorProgram) jobFinished(bot Bot, job *Job) {
+	// Jobs cut short by a shutdown stay unfinished in the ledger and resume after a restart
+	if job.State == JobFailed && errors.Is(job.Err, context.Canceled) {
+		log.Printf("⏸️ [%s] [ConductorProgram] Job #%s interrupted", bot.GetName(), job.ID)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	log.Printf("🧾 [%s] [ConductorProgram] Job #%s %s in %s", bot.GetName(), job.ID, job.State, job.Finished.Sub(job.Started).Round(time.Millisecond))
+	p.ledger.Transition(job.ID, job.State, job.Err)
+	p.persist()
 
-	// ✅ Initialize Notifier
-	var notifier core.Notifier
-	if p.ProgramConfig.HubConfig.Socket != "" {
-		wsNotifier, err := core.NewWebSocketNotifier(p.ProgramConfig.HubConfig.Socket)
-		if err != nil {
-			log.Println("❌ WebSocket unavailable, falling back to Logger")
-			notifier = &core.LoggerNotifier{}
-		} else {
-			notifier = wsNotifier
+	if job.State != JobFailed || p.ctx.Err() != nil {
+		return
+	}
+	p.replyFailed(bot, job)
+}
+
+// replyFailed tells the user who queued a job that it failed
+func (p *ConductorProgram) replyFailed(bot Bot, job *Job) {
+	text := fmt.Sprintf("⚠️ Job #%s failed: %v", job.ID, job.Err)
+	switch {
+	case errors.Is(job.Err, context.DeadlineExceeded):
+		deadline := job.Timeout
+		if deadline <= 0 {
+			deadline = p.ProgramConfig.WorkerConfig.JobDeadline()
 		}
-	} else {
-		notifier = &core.LoggerNotifier{}
+		text = fmt.Sprintf("⌛ Job #%s ran out of time after %s.", job.ID, deadline)
+	case errors.Is(job.Err, grpcclient.ErrCrawlerUnavailable):
+		text = fmt.Sprintf("🚧 Crawler unavailable, job #%s could not run.", job.ID)
 	}
+	p.reply(bot, &core.BusMessage{ChannelID: job.ChannelID, Payload: core.ContentStructure{Metadata: job.SessionID}}, text)
+}
 
-	// ✅ Start Crawl Job
-	stream, err := p.CrawlerClient.StartCrawl(ctx, &pb.CrawlRequest{
-		Url:   remoteJob.Payload,
-		JobId: remoteJob.SessionID,
-	})
+// ✅ **Initialize gRPC Client in the Program**
+func (p *ConductorProgram) InitCrawlerClient(config core.WorkerConfig) error {
+	client, err := grpcclient.NewCrawlerClient(config)
 	if err != nil {
-		notifier.SendMessage(core.SocketRequest{
-			Type:      "error",
-			ChannelID: remoteJob.ChannelID,
-			Metadata:  remoteJob.SessionID,
-			Text:      "Failed to start crawl: " + err.Error(),
-			CreatedAt: time.Now().Unix(),
-		})
-		return
+		return err
 	}
 
-	// ✅ Handle Response
-	p.handleWorkerResponse(bot, stream, remoteJob, notifier)
+	p.Crawler = client
+	return nil
 }
 
-// ✅ **Handles gRPC Crawl Response via Notifier**
-func (p *ConductorProgram) handleWorkerResponse(bot Bot, stream pb.CrawlerService_StartCrawlClient, remoteJob core.RemoteJob, notifier core.Notifier) {
-	var jobID string
-	for {
-		resp, err := stream.Recv()
-		if err != nil {
-			// ✅ Check if stream closed unexpectedly
-			if err == io.EOF {
-				log.Println("✅ gRPC Stream reached EOF gracefully")
-			} else {
-				log.Printf("❌ gRPC Stream Closed Unexpectedly: %v", err)
-			}
-			break
+// ✅ **Report the crawlers' health and circuit breakers for the bot status**
+func (p *ConductorProgram) Health() []Health {
+	var checks []Health
+	if p.Crawler != nil {
+		checks = append(checks, crawlerHealth("crawler", p.Crawler))
+	}
+
+	verbs := make([]string, 0, len(p.routes))
+	for verb, route := range p.routes {
+		if route.crawler != nil {
+			verbs = append(verbs, verb)
 		}
+	}
+	sort.Strings(verbs)
+	for _, verb := range verbs {
+		checks = append(checks, crawlerHealth("crawler:"+verb, p.routes[verb].crawler))
+	}
+	return checks
+}
+
+func crawlerHealth(service string, client *grpcclient.CrawlerClient) Health {
+	health := client.Health()
+	return Health{
+		Service:   service,
+		Address:   health.Address,
+		Status:    health.Status,
+		Breaker:   string(health.Breaker),
+		Error:     health.Error,
+		CheckedAt: health.CheckedAt,
+	}
+}
 
+// crawlerFor returns the crawler of a command verb, or the default crawler
+func (p *ConductorProgram) crawlerFor(verb string) *grpcclient.CrawlerClient {
+	if route := p.routes[verb]; route != nil && route.crawler != nil {
+		return route.crawler
+	}
+	return p.Crawler
+}
+
+// ✅ **Send Crawl Request**, streaming progress until the crawl ends or ctx
+// does. progress, if set, receives the worker job ID and every progress message
+func (p *ConductorProgram) StartWorkerJob(ctx context.Context, bot Bot, remoteJob core.RemoteJob, progress func(workerJobID, message string)) error {
+	crawler := p.crawlerFor(remoteJob.Verb)
+	if crawler == nil {
+		return fmt.Errorf("crawler client is not initialized")
+	}
+
+	if err := Sleep(ctx, time.Duration(p.ProgramConfig.ResponseDelay)*time.Second); err != nil {
+		return err
+	}
+
+	notifier := p.newNotifier()
+	p.track(notifier)
+	defer p.release(notifier)
+
+	// ✅ Start Crawl Job and forward its progress
+	var jobID string
+	err := crawler.StartCrawl(ctx, remoteJob.Payload, remoteJob.SessionID, func(resp *pb.CrawlResponse) {
 		log.Printf("🔄 Worker Job [%s] Progress: %s", resp.JobId, resp.Message)
 
 		jobID = resp.JobId
+		if progress != nil {
+			progress(resp.JobId, resp.Message)
+		}
 
 		notifier.SendMessage(core.SocketRequest{
 			Type:      "worker_update",
@@ -164,8 +569,96 @@ func (p *ConductorProgram) handleWorkerResponse(bot Bot, stream pb.CrawlerServic
 			Text:      resp.Message,
 			CreatedAt: time.Now().Unix(),
 		})
+	})
+
+	return p.finishWorkerJob(ctx, bot, remoteJob, notifier, jobID, err)
+}
+
+// ✅ **Reattach to a crawl started before a restart**, following its status
+// until the crawler ends the stream, then delivering the report as usual
+func (p *ConductorProgram) ResumeWorkerJob(ctx context.Context, bot Bot, remoteJob core.RemoteJob, workerJobID string, progress func(workerJobID, message string)) error {
+	crawler := p.crawlerFor(remoteJob.Verb)
+	if crawler == nil {
+		return fmt.Errorf("crawler client is not initialized")
+	}
+
+	notifier := p.newNotifier()
+	p.track(notifier)
+	defer p.release(notifier)
+
+	err := crawler.GetJobStatus(ctx, workerJobID, func(resp *pb.JobStatusResponse) {
+		log.Printf("📡 Job [%s] Status: %s", resp.JobId, resp.Status)
+
+		if progress != nil {
+			progress(workerJobID, resp.Status)
+		}
+
+		notifier.SendMessage(core.SocketRequest{
+			Type:      "worker_update",
+			ChannelID: remoteJob.ChannelID,
+			Metadata:  remoteJob.SessionID,
+			Text:      resp.Status,
+			CreatedAt: time.Now().Unix(),
+		})
+	})
+
+	return p.finishWorkerJob(ctx, bot, remoteJob, notifier, workerJobID, err)
+}
+
+// finishWorkerJob tells the hub the crawl is over and posts the report link,
+// unless the crawl failed or ctx ended first
+func (p *ConductorProgram) finishWorkerJob(ctx context.Context, bot Bot, remoteJob core.RemoteJob, notifier core.Notifier, jobID string, crawlErr error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if crawlErr != nil {
+		log.Printf("❌ Crawl failed: %v", crawlErr)
+		notifier.SendMessage(core.SocketRequest{
+			Type:      "error",
+			ChannelID: remoteJob.ChannelID,
+			Metadata:  remoteJob.SessionID,
+			Text:      crawlErr.Error(),
+			CreatedAt: time.Now().Unix(),
+		})
+	}
+
+	// ✅ Handle Response
+	if err := p.handleWorkerDone(ctx, bot, remoteJob, notifier); err != nil {
+		return err
+	}
+
+	// A failed crawl is reported by the job manager instead of a report link
+	if crawlErr != nil {
+		return crawlErr
+	}
+
+	// Publishing before the relays connect would be lost, as after a restart
+	for !bot.IsReady() {
+		if err := Sleep(ctx, time.Second); err != nil {
+			return err
+		}
+	}
+
+	url := fmt.Sprintf("%s/%s", p.ProgramConfig.CallbackUrl, jobID)
+	message := fmt.Sprintf("🧙🏻‍♂️⚡️ Finished. See report @ %s.", url)
+
+	reply := &core.BusMessage{
+		ChannelID:         remoteJob.ChannelID,
+		ReceiverPublicKey: bot.GetPublicKey(),
+		Payload: core.ContentStructure{
+			Kind:     "message",
+			Metadata: remoteJob.SessionID,
+			Text:     core.SerializeContent(message, "message"),
+		},
 	}
 
+	bot.Publish(reply)
+	return nil
+}
+
+// ✅ **Tells the hub the crawl is over**, giving the WebSocket time to flush
+func (p *ConductorProgram) handleWorkerDone(ctx context.Context, bot Bot, remoteJob core.RemoteJob, notifier core.Notifier) error {
 	// ✅ Ensure WebSocket remains open until messages are fully processed
 	log.Println("✅ Sending worker_done now...")
 	notifier.SendMessage(core.SocketRequest{
@@ -177,11 +670,15 @@ func (p *ConductorProgram) handleWorkerResponse(bot Bot, stream pb.CrawlerServic
 	})
 
 	// ✅ Small delay to ensure WebSocket sends this message
-	time.Sleep(500 * time.Millisecond)
+	if err := Sleep(ctx, 500*time.Millisecond); err != nil {
+		return err
+	}
 
 	// ✅ Ensure gRPC stream fully closes before sending `clear_status`
 	log.Println("✅ Waiting for gRPC shutdown to complete...")
-	time.Sleep(500 * time.Millisecond) // Allow last message to flush
+	if err := Sleep(ctx, 500*time.Millisecond); err != nil { // Allow last message to flush
+		return err
+	}
 
 	log.Println("✅ Sending agent_done now...")
 	notifier.SendMessage(core.SocketRequest{
@@ -193,58 +690,179 @@ func (p *ConductorProgram) handleWorkerResponse(bot Bot, stream pb.CrawlerServic
 	})
 
 	// ✅ Final delay before closing WebSocket
-	time.Sleep(500 * time.Millisecond)
-
-	url := fmt.Sprintf("%s/%s", p.ProgramConfig.CallbackUrl, jobID)
-	message := fmt.Sprintf("🧙🏻‍♂️⚡️ Finished. See report @ %s.", url)
+	return Sleep(ctx, 500*time.Millisecond)
+}
 
-	reply := &core.BusMessage{
-		ChannelID:         remoteJob.ChannelID,
-		ReceiverPublicKey: bot.GetPublicKey(),
-		Payload: core.ContentStructure{
-			Kind:     "message",
-			Metadata: remoteJob.SessionID,
-			Text:     core.SerializeContent(message, "message"),
-		},
+// ✅ **Query a crawl's status** off the mailbox. Every update goes to the hub,
+// so reconnecting sessions catch up, and a condensed summary goes to the thread
+func (p *ConductorProgram) queryJobStatus(bot Bot, message *core.BusMessage, id string) {
+	remoteJob := core.RemoteJob{ChannelID: message.ChannelID, SessionID: message.Payload.Metadata}
+	workerJobID, label := id, id
+	crawler := p.Crawler
+
+	// A job of ours is asked about by the ID its crawler reported. Only
+	// operators may ask about other crawler job IDs
+	entry, found := p.ledger.Get(strings.TrimPrefix(id, "#"))
+	if !found && !strings.HasPrefix(id, "#") {
+		entry, found = p.ledgerEntryOfWorkerJob(id)
+	}
+	switch {
+	case found && entry.User != message.SenderPublicKey:
+		p.reply(bot, message, fmt.Sprintf("⚠️ Could not get the status of job %s: %v", id, ErrNotJobOwner))
+		return
+	case found:
+		label = "#" + entry.ID
+		if route := p.routes[entry.Job.Verb]; route != nil && route.backend != nil {
+			p.reply(bot, message, fmt.Sprintf("📡 Job #%s (%s %s): %s", entry.ID, entry.Job.Verb, entry.Job.Payload, entry.State))
+			return
+		}
+		if entry.WorkerJobID == "" {
+			p.reply(bot, message, fmt.Sprintf("📡 Job #%s (%s): %s, the crawler has not reported on it yet.", entry.ID, entry.Job.Payload, entry.State))
+			return
+		}
+		crawler = p.crawlerFor(entry.Job.Verb)
+		workerJobID, remoteJob = entry.WorkerJobID, entry.Job
+	case strings.HasPrefix(id, "#") || !p.operators[message.SenderPublicKey]:
+		p.reply(bot, message, fmt.Sprintf("⚠️ Could not get the status of job %s: %v", id, ErrUnknownJob))
+		return
+	}
+	if crawler == nil {
+		p.reply(bot, message, fmt.Sprintf("⚠️ Could not get the status of job %s: no crawler is configured", id))
+		return
 	}
 
-	bot.Publish(reply)
+	bot.After(0, func() {
+		ctx, cancel := context.WithTimeout(p.ctx, statusTimeout)
+		defer cancel()
+
+		notifier := p.newNotifier()
+		p.track(notifier)
+		defer p.release(notifier)
+
+		var statuses []string
+		err := crawler.GetJobStatus(ctx, workerJobID, func(resp *pb.JobStatusResponse) {
+			log.Printf("📡 Job [%s] Status: %s", resp.JobId, resp.Status)
+			statuses = append(statuses, resp.Status)
+
+			notifier.SendMessage(core.SocketRequest{
+				Type:      "job_status",
+				ChannelID: remoteJob.ChannelID,
+				Metadata:  remoteJob.SessionID,
+				Text:      resp.Status,
+				CreatedAt: time.Now().Unix(),
+			})
+		})
 
-	// ✅ Now we can safely close the WebSocket
-	notifier.Close()
+		if p.ctx.Err() != nil {
+			return
+		}
+		p.reply(bot, message, summarizeStatus(label, statuses, err))
+	})
 }
 
-type JobRequest struct {
-	Query string `json:"query"`
+// ledgerEntryOfWorkerJob finds the job the crawler knows by workerJobID
+func (p *ConductorProgram) ledgerEntryOfWorkerJob(workerJobID string) (LedgerEntry, bool) {
+	for _, entry := range p.ledger.Entries() {
+		if entry.WorkerJobID == workerJobID {
+			return entry, true
+		}
+	}
+	return LedgerEntry{}, false
 }
 
-func sendJobRequest(url string, query string) error {
-	job := JobRequest{Query: query}
-	body, err := json.Marshal(job)
-	if err != nil {
-		return err
+// summarizeStatus condenses a status stream into one line, dropping repeats
+// and keeping the latest few steps
+func summarizeStatus(id string, statuses []string, err error) string {
+	var steps []string
+	for _, status := range statuses {
+		if len(steps) == 0 || steps[len(steps)-1] != status {
+			steps = append(steps, status)
+		}
+	}
+	if len(steps) > maxStatusSteps {
+		steps = append([]string{"…"}, steps[len(steps)-maxStatusSteps:]...)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	if err != nil {
-		return err
+	switch {
+	case len(steps) == 0 && err != nil:
+		return fmt.Sprintf("⚠️ Could not get the status of job %s: %v", id, err)
+	case len(steps) == 0:
+		return fmt.Sprintf("📡 Job %s: no status reported.", id)
+	case err != nil:
+		return fmt.Sprintf("📡 Job %s: %s (interrupted: %v)", id, strings.Join(steps, " → "), err)
+	}
+	return fmt.Sprintf("📡 Job %s: %s", id, strings.Join(steps, " → "))
+}
+
+// newNotifier connects to the hub, falling back to the log
+func (p *ConductorProgram) newNotifier() core.Notifier {
+	if p.ProgramConfig.HubConfig.Socket == "" {
+		return &core.LoggerNotifier{}
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	notifier, err := core.NewWebSocketNotifier(p.ProgramConfig.HubConfig.Socket)
 	if err != nil {
-		return err
+		log.Println("❌ WebSocket unavailable, falling back to Logger")
+		return &core.LoggerNotifier{}
 	}
-	defer resp.Body.Close()
+	return notifier
+}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		log.Printf("Failed to submit job: %s", resp.Status)
-		return err
+// ✅ **Stop drops queued jobs, gives running ones until ctx ends and closes
+// their hub and crawler connections**
+func (p *ConductorProgram) Stop(ctx context.Context) error {
+	p.stopOnce.Do(func() { p.stopErr = p.stop(ctx) })
+	return p.stopErr
+}
+
+func (p *ConductorProgram) stop(ctx context.Context) error {
+	if p.jobs != nil {
+		if err := p.jobs.Stop(ctx); err != nil {
+			log.Printf("⚠️ [ConductorProgram] %v", err)
+		}
+	}
+	if p.cancel != nil {
+		p.cancel()
 	}
 
-	log.Println("✅ Job submitted successfully!")
-	return nil
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for notifier := range p.notifiers {
+		notifier.Close()
+		delete(p.notifiers, notifier)
+	}
+
+	return p.closeWorkers()
+}
+
+// closeWorkers closes the default crawler and the worker backends
+func (p *ConductorProgram) closeWorkers() error {
+	var errs []error
+	if p.Crawler != nil {
+		errs = append(errs, p.Crawler.Close())
+	}
+	for _, route := range p.routes {
+		errs = append(errs, route.close())
+	}
+	return errors.Join(errs...)
+}
+
+func (p *ConductorProgram) track(notifier core.Notifier) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.notifiers[notifier] = struct{}{}
+}
+
+// release closes the notifier once its job is done, unless Stop already did
+func (p *ConductorProgram) release(notifier core.Notifier) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, found := p.notifiers[notifier]; found {
+		notifier.Close()
+		delete(p.notifiers, notifier)
+	}
 }
 
 func createSet(arr []string) map[string]bool {
@@ -0,0 +1,50 @@
+package programs
+
+import (
+	"agent/core"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestConductor builds a Conductor with a ledger but no crawler
+func newTestConductor(t *testing.T, config core.ProgramConfig) *ConductorProgram {
+	t.Helper()
+
+	config.Type = "ConductorProgram"
+	program, err := New(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := program.(*ConductorProgram)
+	p.ledger = NewJobLedger(nil)
+	return p
+}
+
+func TestStatusQueriesCheckTheLedger(t *testing.T) {
+	const operator = "0000000000000000000000000000000000000000000000000000000000000001"
+	p := newTestConductor(t, core.ProgramConfig{Operators: []string{operator}})
+	p.ledger.Start("1", "alice", core.RemoteJob{Payload: "https://example.com"}, time.Now())
+	bot := &fakeBot{}
+
+	tests := []struct {
+		name   string
+		sender string
+		id     string
+		want   string
+	}{
+		{"own job", "alice", "1", "the crawler has not reported on it yet"},
+		{"someone else's job", "bob", "#1", ErrNotJobOwner.Error()},
+		{"unknown job number", "alice", "#7", ErrUnknownJob.Error()},
+		{"raw crawler ID", "alice", "w-123", ErrUnknownJob.Error()},
+		{"raw crawler ID from an operator", operator, "w-123", "no crawler is configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p.queryJobStatus(bot, &core.BusMessage{SenderPublicKey: tt.sender}, tt.id)
+			if reply := bot.last(); !strings.Contains(reply, tt.want) {
+				t.Fatalf("reply = %q, want %q", reply, tt.want)
+			}
+		})
+	}
+}
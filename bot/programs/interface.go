@@ -1,19 +1,105 @@
 package programs
 
-import "agent/core"
+import (
+	"agent/core"
+	"context"
+	"time"
+)
 
-// **BotProgram** defines a contract for all bot programs
+// **BotProgram** defines a contract for all bot programs. The context passed
+// to Run ends when the bot stops or is replaced by a reload, and programs
+// pass it on to their network calls and delays so they abort at once
 type BotProgram interface {
-	Run(bot Bot, message *core.BusMessage) string
+	Run(ctx context.Context, bot Bot, message *core.BusMessage) Result
 	ShouldRun(message *core.BusMessage) bool
 	IsActive() bool
 }
 
+// **Initializer** is implemented by programs that open resources, such as
+// client connections, before their first run. The context lives as long as
+// the bot. A program whose Init fails is not attached
+type Initializer interface {
+	Init(ctx context.Context, bot Bot) error
+}
+
+// **Stopper** is implemented by programs that hold resources, such as open
+// jobs or sockets, which must be released when the bot shuts down or the
+// program finishes. Stop may be called more than once
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+// **HealthReporter** is implemented by programs that depend on outside
+// services, such as crawlers. Health is called for status requests from
+// outside the mailbox, so it must be safe for concurrent use
+type HealthReporter interface {
+	Health() []Health
+}
+
+// **Health** is the last known state of a service a program depends on
+type Health struct {
+	Service   string    `json:"service"`
+	Address   string    `json:"address,omitempty"`
+	Status    string    `json:"status"`            // e.g. serving, not_serving, unreachable or unknown
+	Breaker   string    `json:"breaker,omitempty"` // closed, open or half_open
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at,omitempty"`
+}
+
+// **State** loads and saves the state of one program of one bot
+type State interface {
+	Load(out interface{}) (bool, error)
+	Save(value interface{}) error
+}
+
+// **Stateful** is implemented by programs whose progress, such as run
+// counts, must survive restarts. LoadState is called once before Init and
+// SaveState after every run
+type Stateful interface {
+	LoadState(state State) error
+	SaveState(state State) error
+}
+
+// **RunState** is the progress shared by the built-in programs
+type RunState struct {
+	IsRunning       bool `json:"is_running"`
+	CurrentRunCount int  `json:"current_run_count"`
+}
+
+// **PeerAware** is implemented by programs that address the other bots, so
+// their peer list can follow bots being added or removed on reload
+type PeerAware interface {
+	SetPeers(peers []string)
+}
+
 // **Bot** allows programs to interact with any bot
 type Bot interface {
 	GetName() string
 	GetAliases() []string
 	GetPublicKey() string
+	GetChannelID() string // The configured channel, empty for bots that only send DMs
+	IsReady() bool        // Connected to its relays and listening
 	GetNextReceiver(p *ChatterProgram) string
 	Publish(message *core.BusMessage)
+
+	// ExecutePrograms hands a message to the bot's programs as if it had
+	// been received, so one program can trigger another
+	ExecutePrograms(message *core.BusMessage)
+
+	// After runs fn once the delay has passed, outside the bot's mailbox.
+	// Programs use it for delayed replies and long jobs instead of sleeping
+	After(delay time.Duration, fn func())
+}
+
+// ✅ **Sleep** waits for the delay, returning the context's error if it ends first
+func Sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
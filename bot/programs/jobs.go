@@ -0,0 +1,359 @@
+package programs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull   = errors.New("job queue is full")
+	ErrUnknownJob  = errors.New("no such job")
+	ErrNotJobOwner = errors.New("job belongs to someone else")
+	ErrJobsStopped = errors.New("job manager is stopped")
+)
+
+// **JobState** tells where a job is in its lifecycle
+type JobState string
+
+const (
+	JobQueued    JobState = "queued"
+	JobRunning   JobState = "running"
+	JobDone      JobState = "done"
+	JobFailed    JobState = "failed"
+	JobCancelled JobState = "cancelled"
+)
+
+// **Job** is one unit of work submitted by a user
+type Job struct {
+	ID        string
+	User      string // Public key of the submitter, jobs are queued fairly per user
+	ChannelID string
+	SessionID string
+	Payload   string
+	Verb      string        // Worker command, empty for a crawl of the default crawler
+	Timeout   time.Duration // Overrides the manager's deadline when set
+
+	State    JobState
+	Err      error
+	Queued   time.Time
+	Started  time.Time
+	Finished time.Time
+
+	cancel context.CancelFunc
+}
+
+// **JobManager** runs jobs on a bounded pool of workers. Waiting jobs are
+// kept in one FIFO queue per user and served round robin, so a user who
+// submits many jobs cannot starve the others
+type JobManager struct {
+	mu       sync.Mutex
+	workers  int
+	capacity int           // Queued jobs accepted across all users
+	timeout  time.Duration // Deadline of each job once it starts
+	run      func(ctx context.Context, job *Job) error
+	done     func(job *Job) // Called after a job leaves the running set, may be nil
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	nextID   int
+	queues   map[string][]*Job // Waiting jobs by user
+	users    []string          // Users with waiting jobs, in serving order
+	queued   int
+	running  map[string]*Job
+	finished []*Job // Most recent last, bounded by keepFinished
+}
+
+const keepFinished = 20
+
+// ✅ **Create a job manager**. run does the work and must return once its
+// context ends
+func NewJobManager(ctx context.Context, workers, capacity int, timeout time.Duration, run func(context.Context, *Job) error, done func(*Job)) *JobManager {
+	ctx, cancel := context.WithCancel(ctx)
+	return &JobManager{
+		workers:  workers,
+		capacity: capacity,
+		timeout:  timeout,
+		run:      run,
+		done:     done,
+		ctx:      ctx,
+		cancel:   cancel,
+		queues:   make(map[string][]*Job),
+		running:  make(map[string]*Job),
+	}
+}
+
+// ✅ **Submit** queues the job, starting it at once when a worker is free. It
+// returns the number of jobs that will start before it. Jobs restored after a
+// restart keep the ID they already have
+func (m *JobManager) Submit(job *Job) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ctx.Err() != nil {
+		return 0, ErrJobsStopped
+	}
+	if m.queued >= m.capacity {
+		return 0, ErrQueueFull
+	}
+
+	if job.ID == "" {
+		m.nextID++
+		job.ID = strconv.Itoa(m.nextID)
+	} else if id, err := strconv.Atoi(job.ID); err == nil && id > m.nextID {
+		m.nextID = id
+	}
+	job.State = JobQueued
+	job.Queued = time.Now()
+
+	if len(m.queues[job.User]) == 0 {
+		m.users = append(m.users, job.User)
+	}
+	m.queues[job.User] = append(m.queues[job.User], job)
+	m.queued++
+
+	m.dispatch()
+
+	if job.State == JobRunning {
+		return 0, nil
+	}
+	return m.position(job), nil
+}
+
+// ✅ **ContinueIDs** makes the next job ID follow last, so IDs handed out
+// before a restart are not reused
+func (m *JobManager) ContinueIDs(last int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if last > m.nextID {
+		m.nextID = last
+	}
+}
+
+// ✅ **Cancel** stops a running job or removes a queued one. Only the user
+// who submitted the job may cancel it
+func (m *JobManager) Cancel(id, user string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job, found := m.running[id]; found {
+		if job.User != user {
+			return Job{}, ErrNotJobOwner
+		}
+		job.State = JobCancelled
+		job.cancel()
+		return *job, nil
+	}
+
+	for owner, queue := range m.queues {
+		for i, job := range queue {
+			if job.ID != id {
+				continue
+			}
+			if owner != user {
+				return Job{}, ErrNotJobOwner
+			}
+			m.queues[owner] = append(queue[:i:i], queue[i+1:]...)
+			m.queued--
+			if len(m.queues[owner]) == 0 {
+				m.dropUser(owner)
+			}
+			job.State = JobCancelled
+			job.Finished = time.Now()
+			m.remember(job)
+			return *job, nil
+		}
+	}
+	return Job{}, ErrUnknownJob
+}
+
+// ✅ **Running** lists running jobs, followed by recently finished ones
+func (m *JobManager) Running() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var jobs []Job
+	for _, job := range m.running {
+		jobs = append(jobs, *job)
+	}
+	sortJobs(jobs)
+	for i := len(m.finished) - 1; i >= 0; i-- {
+		jobs = append(jobs, *m.finished[i])
+	}
+	return jobs
+}
+
+// ✅ **Queue** lists waiting jobs in the order they will start
+func (m *JobManager) Queue() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var jobs []Job
+	for _, job := range m.order() {
+		jobs = append(jobs, *job)
+	}
+	return jobs
+}
+
+// ✅ **Get** returns a job by ID, whether queued, running or recently finished
+func (m *JobManager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job, found := m.running[id]; found {
+		return *job, true
+	}
+	for _, queue := range m.queues {
+		for _, job := range queue {
+			if job.ID == id {
+				return *job, true
+			}
+		}
+	}
+	for _, job := range m.finished {
+		if job.ID == id {
+			return *job, true
+		}
+	}
+	return Job{}, false
+}
+
+// ✅ **Stop** drops waiting jobs and gives running ones until ctx ends
+// before cancelling them
+func (m *JobManager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	for _, user := range m.users {
+		for _, job := range m.queues[user] {
+			job.State = JobCancelled
+		}
+	}
+	m.queues = make(map[string][]*Job)
+	m.users, m.queued = nil, 0
+	m.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		m.cancel()
+		return nil
+	case <-ctx.Done():
+		m.cancel()
+		<-finished
+		return fmt.Errorf("running jobs cancelled: %w", ctx.Err())
+	}
+}
+
+// dispatch starts waiting jobs while workers are free. Callers hold m.mu
+func (m *JobManager) dispatch() {
+	for len(m.running) < m.workers && len(m.users) > 0 {
+		user := m.users[0]
+		job := m.queues[user][0]
+		m.queues[user] = m.queues[user][1:]
+		m.queued--
+
+		// Move the user to the back so the next user gets the next worker
+		m.users = m.users[1:]
+		if len(m.queues[user]) > 0 {
+			m.users = append(m.users, user)
+		} else {
+			delete(m.queues, user)
+		}
+
+		timeout := m.timeout
+		if job.Timeout > 0 {
+			timeout = job.Timeout
+		}
+		ctx, cancel := context.WithTimeout(m.ctx, timeout)
+		job.cancel = cancel
+		job.State = JobRunning
+		job.Started = time.Now()
+		m.running[job.ID] = job
+
+		m.wg.Add(1)
+		go m.work(ctx, job)
+	}
+}
+
+func (m *JobManager) work(ctx context.Context, job *Job) {
+	defer m.wg.Done()
+
+	err := m.run(ctx, job)
+
+	m.mu.Lock()
+	job.cancel()
+	delete(m.running, job.ID)
+	job.Finished = time.Now()
+	switch {
+	case job.State == JobCancelled:
+	case err != nil:
+		job.State, job.Err = JobFailed, err
+	default:
+		job.State = JobDone
+	}
+	m.remember(job)
+	finished := *job
+	m.dispatch()
+	m.mu.Unlock()
+
+	if m.done != nil {
+		m.done(&finished)
+	}
+}
+
+// order returns waiting jobs in the order dispatch would start them. Callers hold m.mu
+func (m *JobManager) order() []*Job {
+	var jobs []*Job
+	for round := 0; len(jobs) < m.queued; round++ {
+		for _, user := range m.users {
+			if round < len(m.queues[user]) {
+				jobs = append(jobs, m.queues[user][round])
+			}
+		}
+	}
+	return jobs
+}
+
+func (m *JobManager) position(job *Job) int {
+	for i, queued := range m.order() {
+		if queued == job {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (m *JobManager) dropUser(user string) {
+	delete(m.queues, user)
+	for i, u := range m.users {
+		if u == user {
+			m.users = append(m.users[:i], m.users[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *JobManager) remember(job *Job) {
+	m.finished = append(m.finished, job)
+	if len(m.finished) > keepFinished {
+		m.finished = m.finished[len(m.finished)-keepFinished:]
+	}
+}
+
+func sortJobs(jobs []Job) {
+	for i := 1; i < len(jobs); i++ {
+		for j := i; j > 0 && jobs[j].Started.Before(jobs[j-1].Started); j-- {
+			jobs[j], jobs[j-1] = jobs[j-1], jobs[j]
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package programs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// gatedJobs runs jobs that report their payload when they start and wait
+// for a release or their context
+type gatedJobs struct {
+	started  chan string
+	release  chan struct{}
+	finished chan Job
+}
+
+func newGatedJobs() *gatedJobs {
+	return &gatedJobs{
+		started:  make(chan string, 10),
+		release:  make(chan struct{}),
+		finished: make(chan Job, 10),
+	}
+}
+
+func (g *gatedJobs) run(ctx context.Context, job *Job) error {
+	g.started <- job.Payload
+	select {
+	case <-g.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *gatedJobs) done(job *Job) { g.finished <- *job }
+
+func (g *gatedJobs) next(t *testing.T) string {
+	t.Helper()
+
+	select {
+	case payload := <-g.started:
+		return payload
+	case <-time.After(5 * time.Second):
+		t.Fatal("no job started")
+		return ""
+	}
+}
+
+func (g *gatedJobs) result(t *testing.T) Job {
+	t.Helper()
+
+	select {
+	case job := <-g.finished:
+		return job
+	case <-time.After(5 * time.Second):
+		t.Fatal("no job finished")
+		return Job{}
+	}
+}
+
+func TestJobManagerServesUsersFairly(t *testing.T) {
+	jobs := newGatedJobs()
+	m := NewJobManager(context.Background(), 1, 10, time.Minute, jobs.run, jobs.done)
+	defer m.Stop(context.Background())
+
+	submit := []struct {
+		user, payload string
+		position      int
+	}{
+		{"alice", "a1", 0},
+		{"alice", "a2", 1},
+		{"alice", "a3", 2},
+		{"bob", "b1", 2},
+		{"carol", "c1", 3},
+	}
+	for _, s := range submit {
+		position, err := m.Submit(&Job{User: s.user, Payload: s.payload})
+		if err != nil {
+			t.Fatalf("submit %s: %v", s.payload, err)
+		}
+		if position != s.position {
+			t.Errorf("%s is at position %d, want %d", s.payload, position, s.position)
+		}
+	}
+
+	var queue []string
+	for _, job := range m.Queue() {
+		queue = append(queue, job.Payload)
+	}
+
+	// Each user's jobs keep their order, and users take turns
+	want := []string{"a1", "a2", "b1", "c1", "a3"}
+	for i, payload := range want {
+		if got := jobs.next(t); got != payload {
+			t.Fatalf("job %d started %s, want %s (queue was %v)", i, got, payload, queue)
+		}
+		jobs.release <- struct{}{}
+		if job := jobs.result(t); job.Payload != payload || job.State != JobDone {
+			t.Fatalf("finished %s as %s, want %s done", job.Payload, job.State, payload)
+		}
+	}
+}
+
+func TestJobManagerCancel(t *testing.T) {
+	jobs := newGatedJobs()
+	m := NewJobManager(context.Background(), 1, 10, time.Minute, jobs.run, jobs.done)
+	defer m.Stop(context.Background())
+
+	running, queued := &Job{User: "alice", Payload: "running"}, &Job{User: "alice", Payload: "queued"}
+	m.Submit(running)
+	m.Submit(queued)
+	jobs.next(t)
+
+	if _, err := m.Cancel(queued.ID, "bob"); !errors.Is(err, ErrNotJobOwner) {
+		t.Fatalf("cancel by another user = %v, want ErrNotJobOwner", err)
+	}
+	if _, err := m.Cancel("99", "alice"); !errors.Is(err, ErrUnknownJob) {
+		t.Fatalf("cancel of a missing job = %v, want ErrUnknownJob", err)
+	}
+
+	if job, err := m.Cancel(queued.ID, "alice"); err != nil || job.State != JobCancelled {
+		t.Fatalf("cancel queued = %s, %v, want cancelled", job.State, err)
+	}
+	if len(m.Queue()) != 0 {
+		t.Fatal("cancelled job is still queued")
+	}
+
+	if _, err := m.Cancel(running.ID, "alice"); err != nil {
+		t.Fatalf("cancel running: %v", err)
+	}
+	if job := jobs.result(t); job.ID != running.ID || job.State != JobCancelled {
+		t.Fatalf("finished %s as %s, want the running job cancelled", job.ID, job.State)
+	}
+	if job, found := m.Get(queued.ID); !found || job.State != JobCancelled {
+		t.Fatalf("queued job is %s, want it remembered as cancelled", job.State)
+	}
+}
+
+func TestJobManagerLimits(t *testing.T) {
+	jobs := newGatedJobs()
+	m := NewJobManager(context.Background(), 1, 1, 20*time.Millisecond, jobs.run, jobs.done)
+
+	m.Submit(&Job{User: "alice", Payload: "slow"})
+	jobs.next(t)
+	if _, err := m.Submit(&Job{User: "bob", Payload: "waiting"}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if _, err := m.Submit(&Job{User: "carol", Payload: "rejected"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("submit to a full queue = %v, want ErrQueueFull", err)
+	}
+
+	// The running job hits its deadline, which starts the waiting one
+	if job := jobs.result(t); job.Payload != "slow" || job.State != JobFailed || !errors.Is(job.Err, context.DeadlineExceeded) {
+		t.Fatalf("finished %s as %s (%v), want slow failed by its deadline", job.Payload, job.State, job.Err)
+	}
+	jobs.next(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	m.Stop(ctx)
+	if _, err := m.Submit(&Job{User: "alice"}); !errors.Is(err, ErrJobsStopped) {
+		t.Fatalf("submit after stop = %v, want ErrJobsStopped", err)
+	}
+}
@@ -0,0 +1,193 @@
+package programs
+
+import (
+	"agent/core"
+	"sync"
+	"time"
+)
+
+const (
+	keepLedgerEntries = 50          // Finished jobs kept in the ledger
+	progressSaveEvery = time.Second // Progress messages are persisted at most this often
+)
+
+// **LedgerEntry** is the durable record of one Conductor job
+type LedgerEntry struct {
+	ID          string         `json:"id"`
+	User        string         `json:"user,omitempty"`
+	Job         core.RemoteJob `json:"job"`
+	State       JobState       `json:"state"`
+	WorkerJobID string         `json:"worker_job_id,omitempty"` // Job ID the crawler reported, used to reattach
+	Progress    string         `json:"progress,omitempty"`      // Last progress message
+	Error       string         `json:"error,omitempty"`
+	History     []Transition   `json:"history"`
+}
+
+// **Transition** is one state change of a job
+type Transition struct {
+	State JobState  `json:"state"`
+	At    time.Time `json:"at"`
+}
+
+// ✅ **Finished** tells whether the job reached a final state
+func (e LedgerEntry) Finished() bool {
+	return e.State == JobDone || e.State == JobFailed || e.State == JobCancelled
+}
+
+// **JobLedger** records jobs and their progress so they can be picked up again
+// after a restart. It only keeps entries, the owning program persists them
+type JobLedger struct {
+	mu        sync.Mutex
+	entries   []*LedgerEntry // In submission order
+	lastSaved time.Time
+}
+
+// ✅ **Create a ledger** from entries saved before a restart
+func NewJobLedger(saved []LedgerEntry) *JobLedger {
+	l := &JobLedger{}
+	for i := range saved {
+		entry := saved[i]
+		l.entries = append(l.entries, &entry)
+	}
+	return l
+}
+
+// ✅ **Add** records a queued job. A job that already started is left as is
+func (l *JobLedger) Add(id, user string, job core.RemoteJob, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.add(id, user, job, at)
+}
+
+// ✅ **Transition** moves a job to a new state
+func (l *JobLedger) Transition(id string, state JobState, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := l.find(id)
+	if entry == nil {
+		return
+	}
+	entry.State = state
+	entry.History = append(entry.History, Transition{State: state, At: time.Now()})
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	l.prune()
+}
+
+// ✅ **Start** marks a job running, recording it first if Add has not yet
+func (l *JobLedger) Start(id, user string, job core.RemoteJob, queued time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := l.add(id, user, job, queued)
+	entry.State = JobRunning
+	entry.History = append(entry.History, Transition{State: JobRunning, At: time.Now()})
+}
+
+// ✅ **Progress** records the worker job ID and latest progress message. It
+// reports whether the change is worth persisting now
+func (l *JobLedger) Progress(id, workerJobID, message string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := l.find(id)
+	if entry == nil {
+		return false
+	}
+
+	save := workerJobID != "" && entry.WorkerJobID != workerJobID
+	if workerJobID != "" {
+		entry.WorkerJobID = workerJobID
+	}
+	entry.Progress = message
+
+	if save || time.Since(l.lastSaved) >= progressSaveEvery {
+		l.lastSaved = time.Now()
+		return true
+	}
+	return false
+}
+
+// ✅ **Get** returns a job's entry
+func (l *JobLedger) Get(id string) (LedgerEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if entry := l.find(id); entry != nil {
+		return *entry, true
+	}
+	return LedgerEntry{}, false
+}
+
+// ✅ **Entries** returns a copy of every entry, for saving
+func (l *JobLedger) Entries() []LedgerEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := make([]LedgerEntry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		copied := *entry
+		copied.History = append([]Transition(nil), entry.History...)
+		entries = append(entries, copied)
+	}
+	return entries
+}
+
+// ✅ **Unfinished** returns jobs that were queued or running, oldest first
+func (l *JobLedger) Unfinished() []LedgerEntry {
+	var unfinished []LedgerEntry
+	for _, entry := range l.Entries() {
+		if !entry.Finished() {
+			unfinished = append(unfinished, entry)
+		}
+	}
+	return unfinished
+}
+
+func (l *JobLedger) add(id, user string, job core.RemoteJob, at time.Time) *LedgerEntry {
+	if entry := l.find(id); entry != nil {
+		return entry
+	}
+
+	entry := &LedgerEntry{
+		ID:      id,
+		User:    user,
+		Job:     job,
+		State:   JobQueued,
+		History: []Transition{{State: JobQueued, At: at}},
+	}
+	l.entries = append(l.entries, entry)
+	return entry
+}
+
+func (l *JobLedger) find(id string) *LedgerEntry {
+	for _, entry := range l.entries {
+		if entry.ID == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// prune drops the oldest finished entries beyond keepLedgerEntries. Unfinished
+// entries are always kept
+func (l *JobLedger) prune() {
+	finished := 0
+	for _, entry := range l.entries {
+		if entry.Finished() {
+			finished++
+		}
+	}
+
+	kept := l.entries[:0]
+	for _, entry := range l.entries {
+		if entry.Finished() && finished > keepLedgerEntries {
+			finished--
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	l.entries = kept
+}
@@ -0,0 +1,121 @@
+package programs
+
+import (
+	"agent/core"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobLedgerRoundTrip(t *testing.T) {
+	queued := time.Now().Add(-time.Minute)
+	ledger := NewJobLedger(nil)
+	ledger.Add("1", "alice", core.RemoteJob{Payload: "https://one.example.com"}, queued)
+	ledger.Start("2", "bob", core.RemoteJob{Payload: "https://two.example.com"}, queued)
+	ledger.Progress("2", "w-2", "crawled 10 pages")
+	ledger.Start("3", "alice", core.RemoteJob{Payload: "https://three.example.com"}, queued)
+	ledger.Transition("3", JobFailed, errors.New("crawler gone"))
+
+	data, err := json.Marshal(ledger.Entries())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved []LedgerEntry
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	restored := NewJobLedger(saved)
+
+	running, _ := restored.Get("2")
+	if running.State != JobRunning || running.User != "bob" || running.WorkerJobID != "w-2" || running.Progress != "crawled 10 pages" {
+		t.Fatalf("running job restored as %+v", running)
+	}
+	if len(running.History) != 2 || !running.History[0].At.Equal(queued) {
+		t.Fatalf("history restored as %+v, want queued at %s then running", running.History, queued)
+	}
+	failed, _ := restored.Get("3")
+	if !failed.Finished() || failed.Error != "crawler gone" {
+		t.Fatalf("failed job restored as %+v", failed)
+	}
+
+	var unfinished []string
+	for _, entry := range restored.Unfinished() {
+		unfinished = append(unfinished, entry.ID)
+	}
+	if got := strings.Join(unfinished, ","); got != "1,2" {
+		t.Fatalf("unfinished = %s, want 1,2", got)
+	}
+}
+
+func TestJobLedgerKeepsUnfinishedJobs(t *testing.T) {
+	ledger := NewJobLedger(nil)
+	ledger.Add("waiting", "alice", core.RemoteJob{}, time.Now())
+	for i := 0; i < keepLedgerEntries+5; i++ {
+		id := strings.Repeat("x", i+1)
+		ledger.Start(id, "bob", core.RemoteJob{}, time.Now())
+		ledger.Transition(id, JobDone, nil)
+	}
+
+	entries := ledger.Entries()
+	if len(entries) != keepLedgerEntries+1 || entries[0].ID != "waiting" {
+		t.Fatalf("kept %d entries starting with %s, want %d starting with the waiting job", len(entries), entries[0].ID, keepLedgerEntries+1)
+	}
+	if _, found := ledger.Get("x"); found {
+		t.Fatal("oldest finished job was kept")
+	}
+}
+
+func TestConductorRecoversUnfinishedJobs(t *testing.T) {
+	state := &memoryState{}
+	state.Save(conductorState{Jobs: []LedgerEntry{
+		{ID: "3", User: "alice", State: JobDone},
+		{ID: "4", User: "alice", Job: core.RemoteJob{Payload: "https://four.example.com"}, State: JobQueued},
+		{ID: "5", User: "bob", Job: core.RemoteJob{Payload: "https://five.example.com"}, State: JobRunning, WorkerJobID: "w-5"},
+	}})
+
+	p := newTestConductor(t, core.ProgramConfig{})
+	if err := p.LoadState(state); err != nil {
+		t.Fatal(err)
+	}
+
+	// Jobs report whether they would reattach to a crawl already running
+	started := make(chan string, 3)
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.jobs = NewJobManager(p.ctx, 2, 10, time.Minute, func(ctx context.Context, job *Job) error {
+		entry, _ := p.ledger.Get(job.ID)
+		started <- job.ID + ":" + job.Payload + ":" + entry.WorkerJobID
+		<-ctx.Done()
+		return ctx.Err()
+	}, nil)
+	defer p.cancel()
+
+	p.recoverJobs(&fakeBot{})
+
+	var recovered []string
+	for len(recovered) < 2 {
+		select {
+		case job := <-started:
+			recovered = append(recovered, job)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("recovered %v, want jobs 4 and 5", recovered)
+		}
+	}
+	sort.Strings(recovered)
+	if got := strings.Join(recovered, " "); got != "4:https://four.example.com: 5:https://five.example.com:w-5" {
+		t.Fatalf("recovered %s", got)
+	}
+
+	next := &Job{User: "carol"}
+	if _, err := p.jobs.Submit(next); err != nil || next.ID != "6" {
+		t.Fatalf("new job got ID %q (%v), want 6 after the recovered ones", next.ID, err)
+	}
+
+	var saved conductorState
+	if _, err := state.Load(&saved); err != nil || len(saved.Jobs) != 3 {
+		t.Fatalf("saved %d jobs (%v), want the recovered ledger persisted", len(saved.Jobs), err)
+	}
+}
@@ -0,0 +1,53 @@
+package programs
+
+import (
+	"agent/core"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// **ProgramFactory** builds a program from its YAML settings
+type ProgramFactory func(config core.ProgramConfig, peers []string) (BotProgram, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]ProgramFactory)
+)
+
+// ✅ **Register a program type** so it can be referenced from `programs:`
+func Register(programType string, factory ProgramFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[programType]; exists {
+		panic(fmt.Sprintf("programs: type %q registered twice", programType))
+	}
+	registry[programType] = factory
+}
+
+// ✅ **Build a program** from its config entry
+func New(config core.ProgramConfig, peers []string) (BotProgram, error) {
+	registryMu.RLock()
+	factory, found := registry[config.Type]
+	registryMu.RUnlock()
+
+	if !found {
+		return nil, fmt.Errorf("unknown program type: %q", config.Type)
+	}
+
+	return factory(config, peers)
+}
+
+// ✅ **List registered program types**
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]string, 0, len(registry))
+	for programType := range registry {
+		types = append(types, programType)
+	}
+	sort.Strings(types)
+	return types
+}
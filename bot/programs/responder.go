@@ -2,6 +2,8 @@ package programs
 
 import (
 	"agent/core"
+	"context"
+	"fmt"
 	"log"
 	"strconv"
 	"time"
@@ -11,17 +13,18 @@ import (
 
 // **ResponderProgram** - Handles responding when mentioned
 type ResponderProgram struct {
-	IsRunning       bool
-	CurrentRunCount int
+	Runner
 
 	ProgramConfig core.ProgramConfig
-
-	Peers []string
 }
 
-// ✅ **Check if the program is active**
-func (p *ResponderProgram) IsActive() bool {
-	return p.IsRunning
+func init() {
+	Register("ResponderProgram", func(config core.ProgramConfig, peers []string) (BotProgram, error) {
+		return &ResponderProgram{
+			ProgramConfig: config,
+			Runner:        Runner{Peers: peers},
+		}, nil
+	})
 }
 
 // ✅ **Should this program run?**
@@ -30,13 +33,13 @@ func (p *ResponderProgram) ShouldRun(message *core.BusMessage) bool {
 }
 
 // ✅ **Run Responder Logic**
-func (p *ResponderProgram) Run(bot Bot, message *core.BusMessage) string {
+func (p *ResponderProgram) Run(ctx context.Context, bot Bot, message *core.BusMessage) Result {
 	log.Printf("🏃 [%s] [ResponderProgram] [%d]", bot.GetPublicKey(), p.CurrentRunCount)
 
 	if p.CurrentRunCount >= p.ProgramConfig.MaxRunCount {
 		log.Printf("🛑 [%s] [ResponderProgram] reached max run count. Terminating...", bot.GetPublicKey())
 		p.IsRunning = false
-		return "🔴"
+		return Finished("max run count reached")
 	}
 
 	if !p.IsRunning {
@@ -54,32 +57,30 @@ func (p *ResponderProgram) Run(bot Bot, message *core.BusMessage) string {
 	encodedPublicKey, err := nip19.EncodePublicKey(receiver)
 	if err != nil {
 		log.Printf("❌ Error encoding public key: %v", err)
-		return "🔴"
+		return Failed(fmt.Errorf("could not encode own key: %w", err))
 	}
 
 	if mention == "" || mention != encodedPublicKey {
-		return "🟠 No valid mention"
+		return Skipped("not addressed to this bot")
 	}
 
 	words := core.SplitMessageContent(text)
 	if len(words) < 2 {
 		log.Println("⚠️ Malformed message, missing number.")
-		return "🟠"
+		return Skipped("malformed message, missing number")
 	}
 
 	number, err := strconv.Atoi(words[1])
 	if err != nil {
 		log.Println("❌ Could not parse number:", err)
-		return "🟠"
+		return Skipped("malformed message, number expected")
 	}
 	number++
 
-	time.Sleep(time.Duration(p.ProgramConfig.ResponseDelay) * time.Second)
-
 	encodedPublicKey, err = nip19.EncodePublicKey(message.SenderPublicKey)
 	if err != nil {
 		log.Printf("❌ Error encoding public key: %v", err)
-		return "🔴"
+		return Failed(fmt.Errorf("could not encode sender: %w", err))
 	}
 
 	reply := &core.BusMessage{
@@ -92,6 +93,6 @@ func (p *ResponderProgram) Run(bot Bot, message *core.BusMessage) string {
 		},
 	}
 
-	bot.Publish(reply)
-	return "🟢"
+	bot.After(time.Duration(p.ProgramConfig.ResponseDelay)*time.Second, func() { bot.Publish(reply) })
+	return OK(reply)
 }
@@ -0,0 +1,86 @@
+package programs
+
+import (
+	"agent/core"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// **Status** tells how a program run ended
+type Status string
+
+const (
+	StatusOK       Status = "ok"       // The program acted on the message
+	StatusSkipped  Status = "skipped"  // The message was not for this program, e.g. not addressed to the bot
+	StatusError    Status = "error"    // The program failed
+	StatusFinished Status = "finished" // The program is done and will be removed
+)
+
+// **Result** describes one program run
+type Result struct {
+	Program  string             `json:"program"` // Program type, set by the bot
+	Status   Status             `json:"status"`
+	Reason   string             `json:"reason,omitempty"`
+	Messages []*core.BusMessage `json:"messages,omitempty"` // Replies the run published or scheduled
+	Duration time.Duration      `json:"duration"`           // Set by the bot
+	Err      error              `json:"-"`
+}
+
+// ✅ **OK** reports a run that acted on the message
+func OK(messages ...*core.BusMessage) Result {
+	return Result{Status: StatusOK, Messages: messages}
+}
+
+// ✅ **Skipped** reports a message the program had nothing to do with
+func Skipped(reason string) Result {
+	return Result{Status: StatusSkipped, Reason: reason}
+}
+
+// ✅ **Failed** reports a run that went wrong
+func Failed(err error) Result {
+	return Result{Status: StatusError, Reason: err.Error(), Err: err}
+}
+
+// ✅ **Finished** reports a program that has completed its work
+func Finished(reason string) Result {
+	return Result{Status: StatusFinished, Reason: reason}
+}
+
+// ✅ **String** renders the result for logs
+func (r Result) String() string {
+	signal := map[Status]string{
+		StatusOK:       "🟢",
+		StatusSkipped:  "🟠",
+		StatusError:    "🔴",
+		StatusFinished: "🏁",
+	}[r.Status]
+
+	if r.Reason != "" {
+		return signal + " " + r.Reason
+	}
+	return signal
+}
+
+// ✅ **MarshalJSON** includes the error text
+func (r Result) MarshalJSON() ([]byte, error) {
+	type plain Result
+	out := struct {
+		plain
+		Error string `json:"error,omitempty"`
+	}{plain: plain(r)}
+
+	if r.Err != nil {
+		out.Error = r.Err.Error()
+	}
+	return json.Marshal(out)
+}
+
+// ✅ **Name** returns the registered type of a program, e.g. "ChatterProgram"
+func Name(program BotProgram) string {
+	t := reflect.TypeOf(program)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
@@ -0,0 +1,54 @@
+package programs
+
+// **Runner** holds the run count, running flag and peers of the built-in
+// programs. Programs embed it to get its state and peer handling
+type Runner struct {
+	IsRunning       bool
+	CurrentRunCount int
+
+	Peers []string
+}
+
+// ✅ **Check if the program is active**
+func (r *Runner) IsActive() bool {
+	return r.IsRunning
+}
+
+// ✅ **Replace the peers after a reload**
+func (r *Runner) SetPeers(peers []string) {
+	r.Peers = peers
+}
+
+// ✅ **Restore the run count and running flag saved before a restart**
+func (r *Runner) LoadState(state State) error {
+	var saved RunState
+	found, err := state.Load(&saved)
+	if found {
+		r.restore(saved)
+	}
+	return err
+}
+
+// ✅ **Save the run count and running flag**
+func (r *Runner) SaveState(state State) error {
+	return state.Save(r.runState())
+}
+
+// spend counts a run against the budget of maxRunCount runs. Once the budget
+// is used up it stops the program and reports false
+func (r *Runner) spend(maxRunCount int) bool {
+	if r.CurrentRunCount >= maxRunCount {
+		r.IsRunning = false
+		return false
+	}
+	r.CurrentRunCount++
+	return true
+}
+
+func (r *Runner) runState() RunState {
+	return RunState{IsRunning: r.IsRunning, CurrentRunCount: r.CurrentRunCount}
+}
+
+func (r *Runner) restore(saved RunState) {
+	r.IsRunning, r.CurrentRunCount = saved.IsRunning, saved.CurrentRunCount
+}
@@ -0,0 +1,307 @@
+package programs
+
+import (
+	"agent/core"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+// maxCatchUpRuns caps how many missed runs the "all" policy replays at once
+const maxCatchUpRuns = 100
+
+// **ScheduledProgram** - Publishes a message or triggers the other programs
+// on a cron expression or interval, without waiting for incoming messages
+type ScheduledProgram struct {
+	Runner // Touched by the schedule goroutine, guarded by mu
+
+	ProgramConfig core.ProgramConfig
+
+	cron     *core.CronSchedule // Nil for interval schedules
+	every    time.Duration
+	location *time.Location
+	message  *template.Template
+	receiver string // Hex public key to DM, if any
+
+	mu      sync.Mutex
+	lastRun time.Time // Last scheduled time that fired, persisted for catch-up
+	state   State
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// scheduledState is what a ScheduledProgram persists between restarts
+type scheduledState struct {
+	RunState
+	LastRun time.Time `json:"last_run"`
+}
+
+// scheduleData is passed to the message template
+type scheduleData struct {
+	Bot  string
+	Time time.Time
+	Run  int
+}
+
+func init() {
+	Register("ScheduledProgram", func(config core.ProgramConfig, peers []string) (BotProgram, error) {
+		return NewScheduledProgram(config)
+	})
+}
+
+// ✅ **Build a scheduled program**, parsing its schedule and template up front
+func NewScheduledProgram(config core.ProgramConfig) (*ScheduledProgram, error) {
+	schedule := config.Schedule
+	p := &ScheduledProgram{
+		Runner:        Runner{IsRunning: true},
+		ProgramConfig: config,
+		every:         time.Duration(schedule.Every) * time.Second,
+	}
+
+	var err error
+	if schedule.Cron != "" {
+		if p.cron, err = core.ParseCron(schedule.Cron); err != nil {
+			return nil, err
+		}
+	} else if p.every <= 0 {
+		return nil, fmt.Errorf("schedule needs cron or every")
+	}
+
+	if p.location, err = schedule.Location(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case schedule.Message == "" && schedule.Trigger == "":
+		return nil, fmt.Errorf("schedule needs a message or a trigger")
+	case schedule.Message != "":
+		if p.message, err = template.New("message").Parse(schedule.Message); err != nil {
+			return nil, fmt.Errorf("invalid message template: %w", err)
+		}
+	}
+
+	p.receiver = schedule.Receiver
+	if prefix, value, err := nip19.Decode(schedule.Receiver); err == nil && prefix == "npub" {
+		p.receiver = value.(string)
+	}
+	return p, nil
+}
+
+// ✅ **Check if the program is active**, which the schedule changes from its goroutine
+func (p *ScheduledProgram) IsActive() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.IsRunning
+}
+
+// ✅ **Incoming messages never run it**, the schedule does
+func (p *ScheduledProgram) ShouldRun(message *core.BusMessage) bool {
+	return false
+}
+
+// ✅ **Run** does nothing, see ShouldRun
+func (p *ScheduledProgram) Run(ctx context.Context, bot Bot, message *core.BusMessage) Result {
+	return Skipped("runs on its schedule")
+}
+
+// ✅ **Restore the run count and last run saved before a restart**
+func (p *ScheduledProgram) LoadState(state State) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state = state
+
+	var saved scheduledState
+	found, err := state.Load(&saved)
+	if found {
+		p.restore(saved.RunState)
+		p.lastRun = saved.LastRun
+	}
+	return err
+}
+
+// ✅ **Save the run count and last run**
+func (p *ScheduledProgram) SaveState(state State) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.save(state)
+}
+
+// ✅ **Start the schedule**, catching up on runs missed while stopped
+func (p *ScheduledProgram) Init(ctx context.Context, bot Bot) error {
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+		p.loop(ctx, bot)
+	}()
+	return nil
+}
+
+// ✅ **Stop the schedule**
+func (p *ScheduledProgram) Stop(ctx context.Context) error {
+	if p.cancel == nil {
+		return nil
+	}
+	p.cancel()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *ScheduledProgram) loop(ctx context.Context, bot Bot) {
+	now := time.Now()
+
+	p.mu.Lock()
+	lastRun := p.lastRun
+	p.mu.Unlock()
+
+	if !lastRun.IsZero() {
+		missed := p.missed(lastRun, now)
+		runs := 0
+		switch p.ProgramConfig.Schedule.CatchUp {
+		case "once":
+			runs = min(len(missed), 1)
+		case "all":
+			runs = min(len(missed), maxCatchUpRuns)
+		}
+		if len(missed) > 0 {
+			log.Printf("⏰ [%s] [ScheduledProgram] missed %d runs while stopped, catching up %d", bot.GetName(), len(missed), runs)
+		}
+		// Each catch-up run renders the time it was scheduled for
+		for _, at := range missed[:runs] {
+			if !p.fire(ctx, bot, at) {
+				return
+			}
+		}
+	}
+	p.advance(now)
+
+	for {
+		next := p.next(now)
+		if next.IsZero() {
+			log.Printf("⏰ [%s] [ScheduledProgram] schedule never fires again", bot.GetName())
+			return
+		}
+		if Sleep(ctx, time.Until(next)) != nil {
+			return
+		}
+		if !p.fire(ctx, bot, next) {
+			return
+		}
+
+		// Waiting for the bot to come back online may have taken a while,
+		// schedule from now instead of replaying everything it missed
+		now = next
+		if current := time.Now(); current.After(now) {
+			now = current
+		}
+	}
+}
+
+// fire runs the scheduled action once, reporting false when the program is done
+func (p *ScheduledProgram) fire(ctx context.Context, bot Bot, at time.Time) bool {
+	// Publishing before the relays connect would be lost
+	for !bot.IsReady() {
+		if Sleep(ctx, time.Second) != nil {
+			return false
+		}
+	}
+
+	p.mu.Lock()
+	if !p.spend(p.ProgramConfig.MaxRunCount) {
+		log.Printf("🛑 [%s] [ScheduledProgram] reached max run count. Terminating...", bot.GetName())
+		p.saveLocked()
+		p.mu.Unlock()
+		return false
+	}
+	run := p.CurrentRunCount
+	p.lastRun = at
+	p.saveLocked()
+	p.mu.Unlock()
+
+	schedule := p.ProgramConfig.Schedule
+	if schedule.Trigger != "" {
+		log.Printf("⏰ [%s] [ScheduledProgram] [%d] triggering programs with %q", bot.GetName(), run, schedule.Trigger)
+		bot.ExecutePrograms(&core.BusMessage{
+			SenderPublicKey: bot.GetPublicKey(),
+			ChannelID:       bot.GetChannelID(),
+			Payload:         core.ContentStructure{Kind: "message", Text: schedule.Trigger},
+			Timestamp:       time.Now().Unix(),
+		})
+		return true
+	}
+
+	var text bytes.Buffer
+	if err := p.message.Execute(&text, scheduleData{Bot: bot.GetName(), Time: at.In(p.location), Run: run}); err != nil {
+		log.Printf("❌ [%s] [ScheduledProgram] could not render message: %v", bot.GetName(), err)
+		return true
+	}
+
+	log.Printf("⏰ [%s] [ScheduledProgram] [%d] publishing", bot.GetName(), run)
+	bot.Publish(&core.BusMessage{
+		ReceiverPublicKey: p.receiver,
+		Payload: core.ContentStructure{
+			Kind: "message",
+			Text: core.SerializeContent(text.String(), "message"),
+		},
+		Timestamp: time.Now().Unix(),
+	})
+	return true
+}
+
+// advance records now as handled so runs before it are not caught up again
+func (p *ScheduledProgram) advance(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lastRun.Before(now) {
+		p.lastRun = now
+		p.saveLocked()
+	}
+}
+
+// next returns when the schedule fires after t
+func (p *ScheduledProgram) next(t time.Time) time.Time {
+	if p.cron == nil {
+		return t.Add(p.every)
+	}
+	return p.cron.Next(t.In(p.location))
+}
+
+// missed lists the scheduled times in (from, to] oldest first, up to one
+// past the catch-up cap
+func (p *ScheduledProgram) missed(from, to time.Time) []time.Time {
+	var times []time.Time
+	for t := p.next(from); !t.IsZero() && !t.After(to) && len(times) <= maxCatchUpRuns; t = p.next(t) {
+		times = append(times, t)
+	}
+	return times
+}
+
+func (p *ScheduledProgram) saveLocked() {
+	if p.state == nil {
+		return
+	}
+	if err := p.save(p.state); err != nil {
+		log.Printf("⚠️ [ScheduledProgram] could not save schedule: %v", err)
+	}
+}
+
+func (p *ScheduledProgram) save(state State) error {
+	return state.Save(scheduledState{
+		RunState: p.runState(),
+		LastRun:  p.lastRun,
+	})
+}
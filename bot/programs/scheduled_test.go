@@ -0,0 +1,158 @@
+package programs
+
+import (
+	"agent/core"
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBot records what programs publish and trigger, and runs After at once
+type fakeBot struct {
+	mu       sync.Mutex
+	replies  []string
+	triggers []*core.BusMessage
+}
+
+func (b *fakeBot) GetName() string                          { return "test" }
+func (b *fakeBot) GetAliases() []string                     { return []string{"hype"} }
+func (b *fakeBot) GetPublicKey() string                     { return "bot" }
+func (b *fakeBot) GetChannelID() string                     { return "channel" }
+func (b *fakeBot) IsReady() bool                            { return true }
+func (b *fakeBot) GetNextReceiver(p *ChatterProgram) string { return "" }
+func (b *fakeBot) After(delay time.Duration, fn func())     { fn() }
+
+func (b *fakeBot) Publish(message *core.BusMessage) {
+	var content core.ContentStructure
+	json.Unmarshal([]byte(message.Payload.Text), &content)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.replies = append(b.replies, content.Text)
+}
+
+func (b *fakeBot) ExecutePrograms(message *core.BusMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.triggers = append(b.triggers, message)
+}
+
+// sent returns the replies so far
+func (b *fakeBot) sent() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.replies...)
+}
+
+// last returns the latest reply
+func (b *fakeBot) last() string {
+	replies := b.sent()
+	if len(replies) == 0 {
+		return ""
+	}
+	return replies[len(replies)-1]
+}
+
+// memoryState keeps a program's saved state as JSON, like the real stores
+type memoryState struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (s *memoryState) Load(out interface{}) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(s.data, out)
+}
+
+func (s *memoryState) Save(value interface{}) error {
+	data, err := json.Marshal(value)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = data
+	return err
+}
+
+// eventually polls until check passes
+func eventually(t *testing.T, what string, check func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newTestSchedule(t *testing.T, config core.ProgramConfig) *ScheduledProgram {
+	t.Helper()
+
+	config.Type = "ScheduledProgram"
+	if config.MaxRunCount == 0 {
+		config.MaxRunCount = 100
+	}
+	p, err := NewScheduledProgram(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestScheduledProgramNeedsAnAction(t *testing.T) {
+	if _, err := NewScheduledProgram(core.ProgramConfig{Schedule: core.ScheduleConfig{Every: 60}}); err == nil {
+		t.Fatal("schedule without message or trigger was accepted")
+	}
+}
+
+func TestScheduledCatchUpRendersMissedTimes(t *testing.T) {
+	p := newTestSchedule(t, core.ProgramConfig{Schedule: core.ScheduleConfig{Every: 3600, CatchUp: "all", Message: "{{.Time.Unix}}"}})
+
+	lastRun := time.Now().Add(-210 * time.Minute).Truncate(time.Second)
+	state := &memoryState{}
+	state.Save(scheduledState{RunState: RunState{IsRunning: true}, LastRun: lastRun})
+	if err := p.LoadState(state); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := &fakeBot{}
+	p.Init(context.Background(), bot)
+	defer p.Stop(context.Background())
+	eventually(t, "three catch-up runs", func() bool { return len(bot.sent()) == 3 })
+
+	for i, text := range bot.sent() {
+		want := lastRun.Add(time.Duration(i+1) * time.Hour).Unix()
+		if text != strconv.FormatInt(want, 10) {
+			t.Fatalf("catch-up run %d rendered %s, want %d", i, text, want)
+		}
+	}
+}
+
+func TestScheduledTriggerStopsAtMaxRunCount(t *testing.T) {
+	p := newTestSchedule(t, core.ProgramConfig{MaxRunCount: 2, Schedule: core.ScheduleConfig{Every: 3600, CatchUp: "all", Trigger: "@hype report"}})
+
+	state := &memoryState{}
+	state.Save(scheduledState{RunState: RunState{IsRunning: true}, LastRun: time.Now().Add(-5 * time.Hour)})
+	p.LoadState(state)
+
+	bot := &fakeBot{}
+	p.Init(context.Background(), bot)
+	defer p.Stop(context.Background())
+	eventually(t, "the budget to run out", func() bool { return !p.IsActive() })
+
+	bot.mu.Lock()
+	defer bot.mu.Unlock()
+	if len(bot.triggers) != 2 {
+		t.Fatalf("triggered %d times, want 2", len(bot.triggers))
+	}
+	if message := bot.triggers[0]; message.ChannelID != "channel" || message.Payload.Text != "@hype report" {
+		t.Fatalf("trigger = %+v, want the report in the bot's channel", message)
+	}
+}
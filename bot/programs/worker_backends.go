@@ -0,0 +1,348 @@
+package programs
+
+import (
+	"agent/core"
+	grpcclient "agent/services/grpc"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+const (
+	maxWorkerOutput = 1 << 20 // Bytes read from a worker's answer
+	maxReplyLength  = 2000    // Characters of worker output quoted in a reply
+)
+
+// Default reply of a worker command when its config has none
+const defaultWorkerReply = "✅ {{.Verb}} #{{.Job}}: {{.Output}}"
+
+// **WorkerBackend** runs one job of a Conductor command and returns its raw output
+type WorkerBackend interface {
+	Run(ctx context.Context, args map[string]string) (string, error)
+}
+
+// **workerRoute** is a command verb with its argument schema, reply format
+// and backend. Crawler routes have no WorkerBackend, they stream progress
+// through the Conductor like the default crawler
+type workerRoute struct {
+	verb    string
+	config  core.WorkerBackendConfig
+	args    []core.ArgSpec
+	reply   *template.Template
+	backend WorkerBackend
+	crawler *grpcclient.CrawlerClient
+}
+
+// workerReply is what a reply template sees
+type workerReply struct {
+	Verb   string
+	Job    string
+	Args   map[string]string
+	Output string
+	JSON   interface{} // The output decoded, when it is JSON
+}
+
+// ✅ **Build a route** from its config, connecting crawler backends lazily
+func newWorkerRoute(verb string, config core.WorkerBackendConfig) (*workerRoute, error) {
+	specs := config.Args
+	if len(specs) == 0 && config.Kind == "crawler" {
+		specs = []string{"url"}
+	}
+	args, err := core.ParseArgSpecs(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	text := config.Reply
+	if text == "" {
+		text = defaultWorkerReply
+	}
+	reply, err := template.New(verb).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reply template: %w", err)
+	}
+
+	route := &workerRoute{verb: verb, config: config, args: args, reply: reply}
+	switch config.Kind {
+	case "crawler":
+		route.crawler, err = grpcclient.NewCrawlerClient(config.WorkerConfig)
+	case "http":
+		route.backend = NewHTTPBackend(config)
+	case "exec":
+		route.backend, err = NewExecBackend(config)
+	default:
+		err = fmt.Errorf("unknown worker kind %q", config.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return route, nil
+}
+
+// ✅ **Usage** shows how to call the command, e.g. "weather <city> [days]"
+func (r *workerRoute) usage() string {
+	return strings.TrimSpace(r.verb + " " + core.ArgUsage(r.args))
+}
+
+// ✅ **Format** renders a backend's output with the route's reply template
+func (r *workerRoute) format(job string, args map[string]string, output string) (string, error) {
+	output = strings.TrimSpace(output)
+
+	data := workerReply{Verb: r.verb, Job: job, Args: args, Output: output}
+	if json.Valid([]byte(output)) {
+		json.Unmarshal([]byte(output), &data.JSON)
+	}
+	if runes := []rune(data.Output); len(runes) > maxReplyLength {
+		data.Output = string(runes[:maxReplyLength]) + "…"
+	}
+
+	var text bytes.Buffer
+	if err := r.reply.Execute(&text, data); err != nil {
+		return "", fmt.Errorf("could not format reply: %w", err)
+	}
+	return text.String(), nil
+}
+
+// ✅ **Close** releases the route's crawler connection
+func (r *workerRoute) close() error {
+	if r.crawler != nil {
+		return r.crawler.Close()
+	}
+	return nil
+}
+
+// **HTTPBackend** sends the arguments to a JSON job endpoint, as a JSON
+// object for POST and PUT or as query parameters for GET
+type HTTPBackend struct {
+	URL     string
+	Method  string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// ✅ **Create an HTTP backend**, posting by default
+func NewHTTPBackend(config core.WorkerBackendConfig) *HTTPBackend {
+	method := strings.ToUpper(config.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+	return &HTTPBackend{URL: config.Url, Method: method, Headers: config.Headers, Client: &http.Client{}}
+}
+
+// ✅ **Run** calls the endpoint and returns its answer. The deadline comes from ctx
+func (b *HTTPBackend) Run(ctx context.Context, args map[string]string) (string, error) {
+	target, body := b.URL, io.Reader(nil)
+	if b.Method == http.MethodGet {
+		query := url.Values{}
+		for name, value := range args {
+			query.Set(name, value)
+		}
+		separator := "?"
+		if strings.Contains(target, "?") {
+			separator = "&"
+		}
+		target += separator + query.Encode()
+	} else {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return "", err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, b.Method, target, body)
+	if err != nil {
+		return "", err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range b.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, maxWorkerOutput))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("worker answered %s: %s", resp.Status, snippet(answer))
+	}
+	return string(answer), nil
+}
+
+// **ExecBackend** runs a local executable. Each part of the command line is a
+// template over the named arguments, parts that render empty are left out,
+// and no shell is involved. Argument values starting with "-" are refused so
+// users cannot pass options to the executable, except after a literal "--" part
+type ExecBackend struct {
+	Command   []*template.Template
+	Separator int // Index of the literal "--" part, -1 when there is none
+
+	uses [][]string // Arguments each part renders, nil when a part may render any of them
+}
+
+// ✅ **Create an exec backend**, parsing its command line templates
+func NewExecBackend(config core.WorkerBackendConfig) (*ExecBackend, error) {
+	if len(config.Command) == 0 {
+		return nil, fmt.Errorf("exec backend needs a command")
+	}
+
+	backend := &ExecBackend{Separator: -1}
+	for i, part := range config.Command {
+		tmpl, err := template.New("command").Option("missingkey=zero").Parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command template: %w", err)
+		}
+		if part == "--" && backend.Separator < 0 {
+			backend.Separator = i
+		}
+		backend.Command = append(backend.Command, tmpl)
+		backend.uses = append(backend.uses, templateArgs(tmpl.Tree.Root))
+	}
+	return backend, nil
+}
+
+// ✅ **Run** executes the command and returns what it printed. The process is
+// killed when ctx ends
+func (b *ExecBackend) Run(ctx context.Context, args map[string]string) (string, error) {
+	argv := make([]string, 0, len(b.Command))
+	for i, tmpl := range b.Command {
+		if b.Separator < 0 || i < b.Separator {
+			if err := b.checkArgs(i, args); err != nil {
+				return "", err
+			}
+		}
+
+		var part bytes.Buffer
+		if err := tmpl.Execute(&part, args); err != nil {
+			return "", err
+		}
+		// Optional arguments that were left out drop their part
+		if part.Len() > 0 {
+			argv = append(argv, part.String())
+		}
+	}
+	if len(argv) == 0 {
+		return "", fmt.Errorf("command is empty")
+	}
+
+	stdout, stderr := &limitedBuffer{limit: maxWorkerOutput}, &limitedBuffer{limit: 4096}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("%s: %w: %s", argv[0], err, snippet(stderr.Bytes()))
+	}
+	return stdout.String(), nil
+}
+
+// checkArgs refuses the values rendered into part i that would read as options
+func (b *ExecBackend) checkArgs(i int, args map[string]string) error {
+	names := b.uses[i]
+	if names == nil {
+		for name := range args {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		if value := args[name]; strings.HasPrefix(value, "-") {
+			return fmt.Errorf("%s %q may not start with \"-\"", name, value)
+		}
+	}
+	return nil
+}
+
+// templateArgs lists the arguments a command part refers to as {{.name}}.
+// It returns nil when the part uses the arguments some other way, e.g.
+// through {{range .}}, so it may render any of them
+func templateArgs(node parse.Node) []string {
+	names := []string{}
+	var walk func(node parse.Node) bool
+	walk = func(node parse.Node) bool {
+		switch node := node.(type) {
+		case *parse.ListNode:
+			if node == nil {
+				return true
+			}
+			for _, child := range node.Nodes {
+				if !walk(child) {
+					return false
+				}
+			}
+		case *parse.ActionNode:
+			return walk(node.Pipe)
+		case *parse.PipeNode:
+			if node == nil {
+				return true
+			}
+			for _, cmd := range node.Cmds {
+				for _, arg := range cmd.Args {
+					if !walk(arg) {
+						return false
+					}
+				}
+			}
+		case *parse.IfNode:
+			return walk(node.Pipe) && walk(node.List) && walk(node.ElseList)
+		case *parse.WithNode:
+			return walk(node.Pipe) && walk(node.List) && walk(node.ElseList)
+		case *parse.RangeNode:
+			return false
+		case *parse.FieldNode:
+			names = append(names, node.Ident[0])
+		case *parse.DotNode, *parse.VariableNode, *parse.ChainNode, *parse.TemplateNode:
+			return false
+		}
+		return true
+	}
+	if !walk(node) {
+		return nil
+	}
+	return names
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the
+// rest, so a chatty process neither fills memory nor blocks on a full pipe
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) Bytes() []byte  { return b.buf.Bytes() }
+func (b *limitedBuffer) String() string { return b.buf.String() }
+
+// snippet shortens a worker's error output for a reply
+func snippet(data []byte) string {
+	text := strings.TrimSpace(string(data))
+	if runes := []rune(text); len(runes) > 200 {
+		return string(runes[:200]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,103 @@
+package programs
+
+import (
+	"agent/core"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExecBackendArguments(t *testing.T) {
+	tests := []struct {
+		name    string
+		command []string
+		args    map[string]string
+		want    string // Output, or part of the error when refused is set
+		refused bool
+	}{
+		{
+			name:    "templated option",
+			command: []string{"echo", "--city={{.city}}"},
+			args:    map[string]string{"city": "berlin"},
+			want:    "--city=berlin",
+		},
+		{
+			name:    "hostile value",
+			command: []string{"echo", "{{.path}}"},
+			args:    map[string]string{"path": "-rf"},
+			want:    `path "-rf"`,
+			refused: true,
+		},
+		{
+			name:    "hostile value in a templated option",
+			command: []string{"echo", "--city={{.city}}"},
+			args:    map[string]string{"city": "-rf"},
+			want:    `city "-rf"`,
+			refused: true,
+		},
+		{
+			name:    "value after the separator",
+			command: []string{"echo", "{{.first}}", "--", "{{.text}}"},
+			args:    map[string]string{"first": "a", "text": "-rf /"},
+			want:    "a -- -rf /",
+		},
+		{
+			name:    "hostile value before the separator",
+			command: []string{"echo", "{{.first}}", "--", "{{.text}}"},
+			args:    map[string]string{"first": "-n", "text": "b"},
+			want:    `first "-n"`,
+			refused: true,
+		},
+		{
+			name:    "dropped optional part",
+			command: []string{"echo", "{{.city}}", "{{.days}}", "done"},
+			args:    map[string]string{"city": "berlin"},
+			want:    "berlin done",
+		},
+		{
+			name:    "arguments used through range",
+			command: []string{"echo", "{{range .}}{{.}}{{end}}"},
+			args:    map[string]string{"city": "-rf"},
+			want:    `city "-rf"`,
+			refused: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, err := NewExecBackend(core.WorkerBackendConfig{Kind: "exec", Command: tt.command})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output, err := backend.Run(context.Background(), tt.args)
+			if tt.refused {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("run = %q, %v, want a refusal of %s", output, err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got := strings.TrimSpace(output); got != tt.want {
+				t.Fatalf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecBackendCapsOutput(t *testing.T) {
+	backend, err := NewExecBackend(core.WorkerBackendConfig{Kind: "exec", Command: []string{"head", "-c", "3000000", "/dev/zero"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := backend.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(output) != maxWorkerOutput {
+		t.Fatalf("kept %d bytes, want %d", len(output), maxWorkerOutput)
+	}
+}
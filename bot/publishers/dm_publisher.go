@@ -3,32 +3,29 @@ package publishers
 import (
 	"agent/bot"
 	"agent/core"
-	"context"
 	"log"
 
 	"github.com/nbd-wtf/go-nostr"
-	"github.com/nbd-wtf/go-nostr/nip04"
 )
 
 // DMPublisher handles sending encrypted direct messages (DM) between bots
 type DMPublisher struct{}
 
+func init() {
+	bot.RegisterPublisher("DMPublisher", func(config core.BotConfig, options core.ComponentOptions) (bot.Publisher, error) {
+		return &DMPublisher{}, nil
+	})
+	bot.RegisterEventType("DMResponseEvent", core.DMResponseEvent)
+}
+
 // Publish sends an encrypted direct message to the receiver
 func (publisher *DMPublisher) Broadcast(b *bot.BaseBot, message *core.BusMessage) error {
 	receiverPubKey := message.ReceiverPublicKey
 
-	sk := b.SecretKey
-	// Compute the shared secret
-	shared, err := nip04.ComputeSharedSecret(receiverPubKey, sk)
-	if err != nil {
-		log.Printf("❌ Failed to compute shared secret: %v", err)
-		return err
-	}
-
 	text := message.Payload.Text
 
 	// Encrypt the message
-	encryptedMessage, err := nip04.Encrypt(text, shared)
+	encryptedMessage, err := b.Signer.NIP04Encrypt(b.Context, receiverPubKey, text)
 	if err != nil {
 		log.Printf("❌ Failed to encrypt message: %v", err)
 		return err
@@ -44,19 +41,13 @@ func (publisher *DMPublisher) Broadcast(b *bot.BaseBot, message *core.BusMessage
 	}
 
 	// Sign the event
-	if err := ev.Sign(sk); err != nil {
+	if err := b.Signer.SignEvent(b.Context, &ev); err != nil {
 		log.Printf("❌ Failed to sign event: %v", err)
 		return err
 	}
 
-	// Publish the message via the relay
-	relay := b.Relay
-	if relay == nil {
-		log.Printf("❌ Relay connection is not established")
-		return err
-	}
-
-	if err := relay.Publish(context.Background(), ev); err != nil {
+	// Publish the message via the write relays
+	if err := b.Pool.Publish(b.Context, ev); err != nil {
 		log.Printf("❌ Failed to publish DM: %v", err)
 		return err
 	}
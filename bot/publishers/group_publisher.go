@@ -4,6 +4,7 @@ import (
 	"agent/bot"
 	"agent/bot/handlers"
 	"agent/core"
+	"fmt"
 	"log"
 
 	"github.com/nbd-wtf/go-nostr"
@@ -15,6 +16,16 @@ type GroupPublisher struct {
 	Handler   *handlers.GroupHandler
 }
 
+func init() {
+	bot.RegisterPublisher("GroupPublisher", func(config core.BotConfig, options core.ComponentOptions) (bot.Publisher, error) {
+		if config.ChannelID == "" {
+			return nil, fmt.Errorf("GroupPublisher needs a channel_id")
+		}
+		return &GroupPublisher{ChannelID: config.ChannelID}, nil
+	})
+	bot.RegisterEventType("GroupResponseEvent", core.GroupResponseEvent)
+}
+
 func (publisher *GroupPublisher) Broadcast(b *bot.BaseBot, message *core.BusMessage) error {
 
 	text := message.Payload.Text
@@ -25,19 +36,19 @@ func (publisher *GroupPublisher) Broadcast(b *bot.BaseBot, message *core.BusMess
 		Kind:      nostr.KindChannelMessage,
 		Content:   text,
 		Tags: nostr.Tags{
-			{"e", publisher.ChannelID, b.Relay.URL, "root"},
+			{"e", publisher.ChannelID, b.Pool.PrimaryURL(), "root"},
 		},
 	}
 
-	event.Sign(b.SecretKey)
+	if err := b.Signer.SignEvent(b.Context, &event); err != nil {
+		log.Printf("❌ Failed to sign group message: %v", err)
+		return err
+	}
 
-	if err := b.Relay.Publish(b.Context, event); err != nil {
+	if err := b.Pool.Publish(b.Context, event); err != nil {
 		log.Printf("❌ Failed to publish group message: %v", err)
 	} else {
-		groupID := publisher.ChannelID
-		groupID = groupID[len(groupID)-3:]
-
-		log.Printf("[%s] 🗣️ [%s] %s", b.Config.Name, groupID, message.Payload)
+		log.Printf("[%s] 🗣️ [%s] %s", b.Config.Name, core.ShortID(publisher.ChannelID), message.Payload)
 	}
 	return nil
 }
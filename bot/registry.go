@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"agent/core"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ListenerFactory builds a listener from the bot config and its typed options
+type ListenerFactory func(config core.BotConfig, options core.ComponentOptions) (EventListener, error)
+
+// PublisherFactory builds a publisher from the bot config and its typed options
+type PublisherFactory func(config core.BotConfig, options core.ComponentOptions) (Publisher, error)
+
+// HandlerFactory builds a handler from the bot config and its typed options
+type HandlerFactory func(config core.BotConfig, options core.ComponentOptions, deps HandlerDeps) (EventHandler, error)
+
+// HandlerDeps gives handlers access to the runtime objects they may need
+type HandlerDeps struct {
+	Manager *BotManager
+	Bot     *BaseBot
+}
+
+// registry is a named set of factories guarded by a lock
+type registry[T any] struct {
+	mu        sync.RWMutex
+	kind      string
+	factories map[string]T
+}
+
+func newRegistry[T any](kind string) *registry[T] {
+	return &registry[T]{kind: kind, factories: make(map[string]T)}
+}
+
+func (r *registry[T]) register(name string, factory T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.factories[name]; exists {
+		panic(fmt.Sprintf("bot: %s %q registered twice", r.kind, name))
+	}
+	r.factories[name] = factory
+}
+
+func (r *registry[T]) lookup(name string) (T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	factory, found := r.factories[name]
+	if !found {
+		return factory, fmt.Errorf("unknown %s: %q", r.kind, name)
+	}
+	return factory, nil
+}
+
+func (r *registry[T]) names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	listenerRegistry  = newRegistry[ListenerFactory]("listener")
+	publisherRegistry = newRegistry[PublisherFactory]("publisher")
+	handlerRegistry   = newRegistry[HandlerFactory]("handler")
+	eventTypeRegistry = newRegistry[core.EventType]("event type")
+)
+
+// RegisterListener makes a listener available under the given name
+func RegisterListener(name string, factory ListenerFactory) {
+	listenerRegistry.register(name, factory)
+}
+
+// RegisterPublisher makes a publisher available under the given name
+func RegisterPublisher(name string, factory PublisherFactory) {
+	publisherRegistry.register(name, factory)
+}
+
+// RegisterHandler makes a handler available under the given name
+func RegisterHandler(name string, factory HandlerFactory) {
+	handlerRegistry.register(name, factory)
+}
+
+// RegisterEventType makes an event type available to `event_type:` in configs
+func RegisterEventType(name string, eventType core.EventType) {
+	eventTypeRegistry.register(name, eventType)
+}
+
+// NewListener builds the listener named in the config
+func NewListener(config core.BotConfig) (EventListener, error) {
+	factory, err := listenerRegistry.lookup(config.Listener)
+	if err != nil {
+		return nil, err
+	}
+	return factory(config, config.ListenerOptions)
+}
+
+// NewPublisher builds the publisher named in the config
+func NewPublisher(config core.BotConfig) (Publisher, error) {
+	factory, err := publisherRegistry.lookup(config.Publisher)
+	if err != nil {
+		return nil, err
+	}
+	return factory(config, config.PublisherOptions)
+}
+
+// NewHandler builds the handler named in the config
+func NewHandler(config core.BotConfig, deps HandlerDeps) (EventHandler, error) {
+	factory, err := handlerRegistry.lookup(config.Handler)
+	if err != nil {
+		return nil, err
+	}
+	return factory(config, config.HandlerOptions, deps)
+}
+
+// LookupEventType resolves the event type named in the config
+func LookupEventType(name string) (core.EventType, error) {
+	return eventTypeRegistry.lookup(name)
+}
+
+// Components lists the registered component names by kind
+func Components() map[string][]string {
+	return map[string][]string{
+		"listeners":   listenerRegistry.names(),
+		"publishers":  publisherRegistry.names(),
+		"handlers":    handlerRegistry.names(),
+		"event_types": eventTypeRegistry.names(),
+	}
+}
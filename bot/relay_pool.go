@@ -0,0 +1,475 @@
+package bot
+
+import (
+	"agent/core"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const (
+	rejoinMinDelay = 5 * time.Second
+	rejoinMaxDelay = time.Minute
+	seenCapacity   = 10000
+)
+
+// RelayHealth is a snapshot of a single relay in the pool
+type RelayHealth struct {
+	URL           string    `json:"url"`
+	Read          bool      `json:"read"`
+	Write         bool      `json:"write"`
+	Connected     bool      `json:"connected"`
+	Failures      int       `json:"failures"` // Consecutive connection failures
+	LastError     string    `json:"last_error,omitempty"`
+	LastSeen      time.Time `json:"last_seen"` // Last successful connect, event or publish
+	Published     int       `json:"published"`
+	PublishErrors int       `json:"publish_errors"`
+}
+
+// RelayPool connects a bot to several relays with read and write roles.
+// Subscriptions fan out to every read relay, publishes go to every write relay
+type RelayPool struct {
+	members []*poolMember
+}
+
+type poolMember struct {
+	mu     sync.Mutex
+	relay  *nostr.Relay
+	health RelayHealth
+	closed bool // The pool was closed, relays dialed since are dropped
+}
+
+// NewRelayPool creates a pool for the given relays without connecting
+func NewRelayPool(relays []core.RelayConfig) *RelayPool {
+	pool := &RelayPool{}
+	for _, relay := range relays {
+		pool.members = append(pool.members, &poolMember{
+			health: RelayHealth{
+				URL:   relay.URL,
+				Read:  relay.CanRead(),
+				Write: relay.CanWrite(),
+			},
+		})
+	}
+	return pool
+}
+
+// Connect opens every relay in parallel. It only fails when no read relay
+// or no write relay could be reached
+func (p *RelayPool) Connect(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, member := range p.members {
+		wg.Add(1)
+		go func(m *poolMember) {
+			defer wg.Done()
+			if _, err := m.connect(ctx); err != nil {
+				log.Printf("⚠️ Relay [%s] unavailable: %v", m.health.URL, err)
+			}
+		}(member)
+	}
+	wg.Wait()
+
+	var readable, writable bool
+	for _, health := range p.Health() {
+		readable = readable || (health.Connected && health.Read)
+		writable = writable || (health.Connected && health.Write)
+	}
+
+	switch {
+	case !readable:
+		return fmt.Errorf("no read relay reachable")
+	case !writable:
+		return fmt.Errorf("no write relay reachable")
+	}
+	return nil
+}
+
+// Connected lists the URLs of the relays currently connected
+func (p *RelayPool) Connected() []string {
+	var urls []string
+	for _, health := range p.Health() {
+		if health.Connected {
+			urls = append(urls, health.URL)
+		}
+	}
+	return urls
+}
+
+// PrimaryURL returns the first write relay, used as a relay hint in tags
+func (p *RelayPool) PrimaryURL() string {
+	for _, member := range p.members {
+		if member.health.Write {
+			return member.health.URL
+		}
+	}
+	return ""
+}
+
+// Health returns a snapshot of every relay in the pool
+func (p *RelayPool) Health() []RelayHealth {
+	health := make([]RelayHealth, len(p.members))
+	for i, member := range p.members {
+		member.mu.Lock()
+		health[i] = member.health
+		health[i].Connected = member.relay != nil && member.relay.IsConnected()
+		member.mu.Unlock()
+	}
+	return health
+}
+
+// Publish sends the event to every write relay and succeeds when at least one accepted it
+func (p *RelayPool) Publish(ctx context.Context, event nostr.Event) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+		sent int
+	)
+
+	for _, member := range p.members {
+		if !member.health.Write {
+			continue
+		}
+
+		wg.Add(1)
+		go func(m *poolMember) {
+			defer wg.Done()
+
+			relay, err := m.connect(ctx)
+			if err == nil {
+				err = relay.Publish(ctx, event)
+			}
+			m.recordPublish(err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", m.health.URL, err))
+			} else {
+				sent++
+			}
+		}(member)
+	}
+	wg.Wait()
+
+	if sent == 0 {
+		if len(errs) == 0 {
+			return fmt.Errorf("no write relay configured")
+		}
+		return errors.Join(errs...)
+	}
+
+	for _, err := range errs {
+		log.Printf("⚠️ Publish failed on %v", err)
+	}
+	return nil
+}
+
+// Close disconnects every relay
+func (p *RelayPool) Close() {
+	for _, member := range p.members {
+		member.mu.Lock()
+		if member.relay != nil {
+			member.relay.Close()
+			member.relay = nil
+		}
+		member.closed = true
+		member.mu.Unlock()
+	}
+}
+
+// PoolSubscription merges a subscription across every read relay
+type PoolSubscription struct {
+	// Events emits each event once, whichever relay delivered it first
+	Events chan *nostr.Event
+
+	// EndOfStoredEvents emits once every read relay has sent EOSE or failed
+	EndOfStoredEvents chan struct{}
+
+	// Done is closed when every read relay is down at the same time
+	Done chan struct{}
+
+	cancel context.CancelFunc
+
+	mu           sync.Mutex
+	readers      int
+	startedCount int  // Readers that finished their first attempt
+	eosedCount   int  // Readers that sent EOSE, failed or dropped before it
+	upCount      int  // Readers currently subscribed
+	ended        bool // Done has been closed
+	seen         *seenSet
+}
+
+// Subscribe fans the filters out to every read relay. Relays that drop are
+// rejoined in the background while the others keep delivering events
+func (p *RelayPool) Subscribe(ctx context.Context, filters nostr.Filters) (*PoolSubscription, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	sub := &PoolSubscription{
+		Events:            make(chan *nostr.Event),
+		EndOfStoredEvents: make(chan struct{}, 1),
+		Done:              make(chan struct{}),
+		cancel:            cancel,
+		seen:              newSeenSet(seenCapacity),
+	}
+
+	for _, member := range p.members {
+		if member.health.Read {
+			sub.readers++
+		}
+	}
+	if sub.readers == 0 {
+		cancel()
+		return nil, fmt.Errorf("no read relay configured")
+	}
+
+	for _, member := range p.members {
+		if member.health.Read {
+			go sub.follow(ctx, member, filters)
+		}
+	}
+	return sub, nil
+}
+
+// Unsub closes the subscription on every relay
+func (s *PoolSubscription) Unsub() {
+	s.cancel()
+}
+
+// follow keeps a single relay subscribed until the pool subscription ends
+func (s *PoolSubscription) follow(ctx context.Context, m *poolMember, filters nostr.Filters) {
+	delay := rejoinMinDelay
+	first := true
+	eosed := false // Whether this reader has been counted towards EndOfStoredEvents
+
+	for ctx.Err() == nil {
+		relay, err := m.connect(ctx)
+		var sub *nostr.Subscription
+		if err == nil {
+			sub, err = relay.Subscribe(ctx, filters)
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			m.recordFailure(err)
+		} else {
+			s.up(first)
+			delay = rejoinMinDelay
+			s.forward(ctx, m, relay, sub, &eosed)
+			sub.Unsub()
+
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("⚠️ Relay [%s] dropped, rejoining in %s", m.health.URL, delay)
+		}
+
+		// A reader that failed or dropped before EOSE must not hold the others back
+		if !eosed {
+			eosed = true
+			s.eose()
+		}
+		if err != nil && first {
+			s.started()
+		} else if err == nil {
+			s.down()
+		}
+		first = false
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, rejoinMaxDelay)
+	}
+}
+
+// forward passes a relay's events on until it disconnects
+func (s *PoolSubscription) forward(ctx context.Context, m *poolMember, relay *nostr.Relay, sub *nostr.Subscription, eosed *bool) {
+	for {
+		select {
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			m.touch()
+			if s.seen.add(event.ID) {
+				continue
+			}
+			select {
+			case s.Events <- event:
+			case <-ctx.Done():
+				return
+			}
+
+		case <-sub.EndOfStoredEvents:
+			if !*eosed {
+				*eosed = true
+				s.eose()
+			}
+
+		case reason := <-sub.ClosedReason:
+			log.Printf("⚠️ Relay [%s] closed subscription: %s", m.health.URL, reason)
+			return
+
+		case <-relay.Context().Done():
+			return
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// up records a reader that subscribed successfully
+func (s *PoolSubscription) up(first bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if first {
+		s.startedCount++
+	}
+	s.upCount++
+}
+
+// started records a reader whose first attempt failed
+func (s *PoolSubscription) started() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.startedCount++
+	s.checkEnded()
+}
+
+// down records a reader that lost its subscription
+func (s *PoolSubscription) down() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.upCount--
+	s.checkEnded()
+}
+
+// checkEnded closes Done once every reader has started and none is up. Callers hold s.mu
+func (s *PoolSubscription) checkEnded() {
+	if !s.ended && s.startedCount == s.readers && s.upCount == 0 {
+		s.ended = true
+		close(s.Done)
+		s.cancel()
+	}
+}
+
+// eose emits EndOfStoredEvents once every reader is accounted for
+func (s *PoolSubscription) eose() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.eosedCount++
+	if s.eosedCount == s.readers {
+		s.EndOfStoredEvents <- struct{}{}
+	}
+}
+
+// connect returns the member's relay, dialing it when it is not connected.
+// The dial runs without holding m.mu, so health reads don't wait on the network
+func (m *poolMember) connect(ctx context.Context) (*nostr.Relay, error) {
+	m.mu.Lock()
+	if m.relay != nil && m.relay.IsConnected() {
+		relay := m.relay
+		m.mu.Unlock()
+		return relay, nil
+	}
+	url := m.health.URL
+	m.mu.Unlock()
+
+	relay, err := nostr.RelayConnect(ctx, url)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		m.health.Failures++
+		m.health.LastError = err.Error()
+		return nil, err
+	}
+	if m.closed {
+		relay.Close()
+		return nil, fmt.Errorf("relay pool closed")
+	}
+	// Another caller may have connected while this one was dialing
+	if m.relay != nil && m.relay.IsConnected() {
+		relay.Close()
+		return m.relay, nil
+	}
+
+	m.relay = relay
+	m.health.Failures = 0
+	m.health.LastSeen = time.Now()
+	return relay, nil
+}
+
+func (m *poolMember) touch() {
+	m.mu.Lock()
+	m.health.LastSeen = time.Now()
+	m.mu.Unlock()
+}
+
+func (m *poolMember) recordFailure(err error) {
+	m.mu.Lock()
+	m.health.LastError = err.Error()
+	m.mu.Unlock()
+}
+
+func (m *poolMember) recordPublish(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		m.health.PublishErrors++
+		m.health.LastError = err.Error()
+		return
+	}
+	m.health.Published++
+	m.health.LastSeen = time.Now()
+}
+
+// seenSet remembers the most recent event IDs
+type seenSet struct {
+	mu    sync.Mutex
+	ids   map[string]struct{}
+	order []string
+	next  int
+}
+
+func newSeenSet(capacity int) *seenSet {
+	return &seenSet{
+		ids:   make(map[string]struct{}, capacity),
+		order: make([]string, capacity),
+	}
+}
+
+// add records the ID and reports whether it was already present
+func (s *seenSet) add(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.ids[id]; found {
+		return true
+	}
+
+	if old := s.order[s.next]; old != "" {
+		delete(s.ids, old)
+	}
+	s.order[s.next] = id
+	s.next = (s.next + 1) % len(s.order)
+	s.ids[id] = struct{}{}
+	return false
+}
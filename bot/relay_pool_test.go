@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"agent/core"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// stalledRelay accepts connections and never answers the websocket handshake
+func stalledRelay(t *testing.T) (string, chan struct{}) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	accepted := make(chan struct{}, 1)
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+			select {
+			case accepted <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return "ws://" + listener.Addr().String(), accepted
+}
+
+func TestRelayPoolHealthDoesNotWaitForDials(t *testing.T) {
+	url, accepted := stalledRelay(t)
+	pool := NewRelayPool([]core.RelayConfig{{URL: url}})
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	connected := make(chan error, 1)
+	go func() { connected <- pool.Connect(ctx) }()
+
+	select {
+	case <-accepted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the pool never dialed")
+	}
+
+	read := make(chan []RelayHealth, 1)
+	go func() { read <- pool.Health() }()
+	select {
+	case health := <-read:
+		if len(health) != 1 || health[0].Connected {
+			t.Fatalf("health = %+v during the dial, want one unconnected relay", health)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Health waited for the dial")
+	}
+
+	cancel()
+	if err := <-connected; err == nil {
+		t.Fatal("connect to a stalled relay succeeded")
+	}
+	if health := pool.Health(); health[0].Failures != 1 || health[0].LastError == "" {
+		t.Fatalf("health = %+v after the failed dial, want one recorded failure", health[0])
+	}
+}
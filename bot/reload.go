@@ -0,0 +1,211 @@
+package bot
+
+import (
+	"agent/core"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+)
+
+// ReloadReport lists the bots a reload touched, by name
+type ReloadReport struct {
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Restarted []string `json:"restarted"`
+	Unchanged []string `json:"unchanged"`
+	Failed    []string `json:"failed,omitempty"` // Bots whose new config could not be applied, restarted ones keep their old config
+}
+
+// CreateBot builds a bot and its components from config, wires its EventBus
+// and adds it to the manager without starting it
+func (m *BotManager) CreateBot(config core.BotConfig) (*BaseBot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.createBot(config)
+}
+
+func (m *BotManager) createBot(config core.BotConfig) (*BaseBot, error) {
+	log.Printf("🤖 Starting bot: %s...", config.Name)
+
+	components, err := newBotComponents(config)
+	if err != nil {
+		return nil, err
+	}
+	listener, publisher, eventType := components.listener, components.publisher, components.eventType
+
+	eventBus := NewEventBus(NewBusOptions(config.EventBus))
+
+	bot, err := NewBaseBot(config, listener, publisher, eventBus)
+	if err != nil {
+		eventBus.Close()
+		return nil, err
+	}
+
+	handler, err := NewHandler(config, HandlerDeps{Manager: m, Bot: bot})
+	if err != nil {
+		bot.Stop()
+		eventBus.Close()
+		return nil, err
+	}
+
+	m.AddBot(bot)
+
+	handler.Subscribe(eventBus)
+
+	eventBus.Subscribe(eventType, func(message *core.BusMessage) {
+		if message.Bridged != "" && (bot.Bridge == nil || !bot.Bridge.Publishes(eventType)) {
+			log.Printf("🌉 [%s] Not publishing [%s] message from bridge peer %s", config.Name, eventType, message.Bridged)
+			return
+		}
+		if err := publisher.Broadcast(bot, message); err != nil {
+			log.Printf("❌ [%s] Failed to broadcast message: %v", config.Name, err)
+		}
+	})
+
+	return bot, nil
+}
+
+// botComponents are the parts of a bot that only depend on its config
+type botComponents struct {
+	listener  EventListener
+	publisher Publisher
+	eventType core.EventType
+}
+
+// newBotComponents builds a bot's listener and publisher and looks up its
+// event type. They hold no resources, so a reload can try them before
+// stopping the bot they replace
+func newBotComponents(config core.BotConfig) (botComponents, error) {
+	var (
+		components botComponents
+		err        error
+	)
+	if components.listener, err = NewListener(config); err != nil {
+		return components, err
+	}
+	if components.publisher, err = NewPublisher(config); err != nil {
+		return components, err
+	}
+	if components.eventType, err = LookupEventType(config.EventType); err != nil {
+		return components, err
+	}
+	return components, nil
+}
+
+// Reload applies a new set of configs to the running bots. Removed and changed
+// bots are stopped gracefully, added and changed ones are started, and
+// unchanged bots stay connected with their program peer lists refreshed. A
+// changed bot whose new config cannot be built keeps running its old one
+func (m *BotManager) Reload(ctx context.Context, configs []core.BotConfig) (ReloadReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var report ReloadReport
+	if m.stopped {
+		return report, fmt.Errorf("bots are shutting down")
+	}
+
+	running := make(map[string]*BaseBot)
+	for _, bot := range m.Bots {
+		running[bot.Config.Name] = bot
+	}
+
+	var (
+		toStop   []*BaseBot
+		toStart  []core.BotConfig
+		wanted   = make(map[string]bool)
+		previous = make(map[string]core.BotConfig) // Configs of restarted bots, to fall back to
+		errs     []error
+	)
+	for _, config := range configs {
+		wanted[config.Name] = true
+
+		current, found := running[config.Name]
+		switch {
+		case !found:
+			report.Added = append(report.Added, config.Name)
+			toStart = append(toStart, config)
+		case !current.Config.Equal(config):
+			// A bad edit must not take the running bot down
+			if _, err := newBotComponents(config); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w, keeping the running config", config.Name, err))
+				report.Failed = append(report.Failed, config.Name)
+				continue
+			}
+			report.Restarted = append(report.Restarted, config.Name)
+			previous[config.Name] = current.Config
+			toStop = append(toStop, current)
+			toStart = append(toStart, config)
+		default:
+			report.Unchanged = append(report.Unchanged, config.Name)
+		}
+	}
+	for name, bot := range running {
+		if !wanted[name] {
+			report.Removed = append(report.Removed, name)
+			toStop = append(toStop, bot)
+		}
+	}
+	sort.Strings(report.Removed)
+
+	// 🛑 Stop first so restarted bots release their cursor and dedup files
+	if len(toStop) > 0 {
+		if err := m.stop(ctx, toStop); err != nil {
+			errs = append(errs, err)
+		}
+		m.remove(toStop)
+	}
+
+	var started []*BaseBot
+	for _, config := range toStart {
+		bot, err := m.createBot(config)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", config.Name, err))
+			report.Failed = append(report.Failed, config.Name)
+
+			// ↩️ Bring a restarted bot back with the config it ran before
+			old, restarted := previous[config.Name]
+			if !restarted {
+				continue
+			}
+			if bot, err = m.createBot(old); err != nil {
+				errs = append(errs, fmt.Errorf("%s: could not restore the previous config: %w", config.Name, err))
+				continue
+			}
+			log.Printf("↩️ [%s] Restarted with the previous config", config.Name)
+		}
+		started = append(started, bot)
+	}
+
+	// 🧮 New bots get fresh programs, running ones only learn the new peers
+	for _, bot := range m.Bots {
+		if slices.Contains(started, bot) {
+			m.InitializePrograms(bot)
+		} else {
+			bot.UpdatePeers(m.peersOf(bot))
+		}
+	}
+
+	for _, bot := range started {
+		bot.Supervisor.starting()
+		go bot.Start()
+	}
+
+	log.Printf("🔄 Reloaded: %d added, %d removed, %d restarted, %d unchanged",
+		len(report.Added), len(report.Removed), len(report.Restarted), len(report.Unchanged))
+
+	return report, errors.Join(errs...)
+}
+
+// remove drops stopped bots from the manager. Callers hold m.mu
+func (m *BotManager) remove(bots []*BaseBot) {
+	m.Bots = slices.DeleteFunc(m.Bots, func(bot *BaseBot) bool {
+		return slices.Contains(bots, bot)
+	})
+	for _, bot := range bots {
+		delete(m.Programs, bot)
+	}
+}
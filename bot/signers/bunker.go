@@ -0,0 +1,127 @@
+package signers
+
+import (
+	"agent/core"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip46"
+)
+
+// BunkerClient is the part of a NIP-46 client the bunker signer relies on.
+// *nip46.BunkerClient satisfies it, and so can an in-process stand-in
+type BunkerClient interface {
+	GetPublicKey(ctx context.Context) (string, error)
+	SignEvent(ctx context.Context, event *nostr.Event) error
+	NIP04Encrypt(ctx context.Context, targetPublicKey, plaintext string) (string, error)
+	NIP04Decrypt(ctx context.Context, targetPublicKey, ciphertext string) (string, error)
+	NIP44Encrypt(ctx context.Context, targetPublicKey, plaintext string) (string, error)
+	NIP44Decrypt(ctx context.Context, targetPublicKey, ciphertext string) (string, error)
+}
+
+// BunkerSigner delegates every key operation to a NIP-46 remote signer
+type BunkerSigner struct {
+	client  BunkerClient
+	timeout time.Duration
+	cancel  context.CancelFunc
+}
+
+// NewBunkerSigner wraps an already connected bunker client
+func NewBunkerSigner(client BunkerClient, timeout time.Duration) *BunkerSigner {
+	return &BunkerSigner{client: client, timeout: timeout}
+}
+
+// ConnectBunker connects to the remote signer named in the config
+func ConnectBunker(config core.SignerConfig) (*BunkerSigner, error) {
+	bunkerURL := config.Bunker
+	if config.BunkerEnv != "" {
+		bunkerURL = os.Getenv(config.BunkerEnv)
+	}
+	if bunkerURL == "" {
+		return nil, fmt.Errorf("no bunker URL configured")
+	}
+
+	clientKey, err := clientSecretKey(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := nostr.NewSimplePool(ctx)
+
+	connectCtx, connectCancel := context.WithTimeout(ctx, config.TimeoutDuration())
+	defer connectCancel()
+
+	client, err := nip46.ConnectBunker(connectCtx, clientKey, bunkerURL, pool, func(authURL string) {
+		log.Printf("🔐 Remote signer requests authorization at %s", authURL)
+	})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to connect to bunker: %w", err)
+	}
+
+	signer := NewBunkerSigner(client, config.TimeoutDuration())
+	signer.cancel = cancel
+	return signer, nil
+}
+
+// clientSecretKey returns the key the agent uses to talk to the bunker,
+// generating a throwaway one when none is configured
+func clientSecretKey(config core.SignerConfig) (string, error) {
+	if config.ClientNsecEnv == "" {
+		return nostr.GeneratePrivateKey(), nil
+	}
+
+	value, found := os.LookupEnv(config.ClientNsecEnv)
+	if !found || value == "" {
+		return "", fmt.Errorf("environment variable %s is not set", config.ClientNsecEnv)
+	}
+	return core.DecodeSecretKey(value)
+}
+
+func (s *BunkerSigner) GetPublicKey(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.client.GetPublicKey(ctx)
+}
+
+func (s *BunkerSigner) SignEvent(ctx context.Context, event *nostr.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.client.SignEvent(ctx, event)
+}
+
+func (s *BunkerSigner) NIP04Encrypt(ctx context.Context, receiverPublicKey, plaintext string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.client.NIP04Encrypt(ctx, receiverPublicKey, plaintext)
+}
+
+func (s *BunkerSigner) NIP04Decrypt(ctx context.Context, senderPublicKey, ciphertext string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.client.NIP04Decrypt(ctx, senderPublicKey, ciphertext)
+}
+
+func (s *BunkerSigner) NIP44Encrypt(ctx context.Context, receiverPublicKey, plaintext string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.client.NIP44Encrypt(ctx, receiverPublicKey, plaintext)
+}
+
+func (s *BunkerSigner) NIP44Decrypt(ctx context.Context, senderPublicKey, ciphertext string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.client.NIP44Decrypt(ctx, senderPublicKey, ciphertext)
+}
+
+// Close drops the relay connections used to reach the bunker
+func (s *BunkerSigner) Close() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
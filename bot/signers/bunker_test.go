@@ -0,0 +1,159 @@
+package signers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// fakeBunker stands in for a remote signer, doing the key operations with a
+// local key. A hung bunker never answers and only returns once ctx ends
+type fakeBunker struct {
+	key  *LocalSigner
+	hung bool
+}
+
+func (b *fakeBunker) wait(ctx context.Context) error {
+	if !b.hung {
+		return nil
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b *fakeBunker) GetPublicKey(ctx context.Context) (string, error) {
+	if err := b.wait(ctx); err != nil {
+		return "", err
+	}
+	return b.key.GetPublicKey(ctx)
+}
+
+func (b *fakeBunker) SignEvent(ctx context.Context, event *nostr.Event) error {
+	if err := b.wait(ctx); err != nil {
+		return err
+	}
+	return b.key.SignEvent(ctx, event)
+}
+
+func (b *fakeBunker) NIP04Encrypt(ctx context.Context, target, plaintext string) (string, error) {
+	if err := b.wait(ctx); err != nil {
+		return "", err
+	}
+	return b.key.NIP04Encrypt(ctx, target, plaintext)
+}
+
+func (b *fakeBunker) NIP04Decrypt(ctx context.Context, target, ciphertext string) (string, error) {
+	if err := b.wait(ctx); err != nil {
+		return "", err
+	}
+	return b.key.NIP04Decrypt(ctx, target, ciphertext)
+}
+
+func (b *fakeBunker) NIP44Encrypt(ctx context.Context, target, plaintext string) (string, error) {
+	if err := b.wait(ctx); err != nil {
+		return "", err
+	}
+	return b.key.NIP44Encrypt(ctx, target, plaintext)
+}
+
+func (b *fakeBunker) NIP44Decrypt(ctx context.Context, target, ciphertext string) (string, error) {
+	if err := b.wait(ctx); err != nil {
+		return "", err
+	}
+	return b.key.NIP44Decrypt(ctx, target, ciphertext)
+}
+
+func newLocalSigner(t *testing.T) *LocalSigner {
+	t.Helper()
+
+	signer, err := NewLocalSigner(nostr.GeneratePrivateKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func publicKey(t *testing.T, signer Signer) string {
+	t.Helper()
+
+	pk, err := signer.GetPublicKey(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pk
+}
+
+func TestBunkerSignerSigns(t *testing.T) {
+	key := newLocalSigner(t)
+	signer := NewBunkerSigner(&fakeBunker{key: key}, time.Second)
+	defer signer.Close()
+
+	event := &nostr.Event{Kind: nostr.KindTextNote, Content: "hello", CreatedAt: nostr.Now()}
+	if err := signer.SignEvent(context.Background(), event); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if event.PubKey != publicKey(t, key) {
+		t.Fatalf("signed by %s, want the bunker's key", event.PubKey)
+	}
+	if ok, err := event.CheckSignature(); !ok || err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+}
+
+func TestBunkerSignerRoundTrips(t *testing.T) {
+	signer := NewBunkerSigner(&fakeBunker{key: newLocalSigner(t)}, time.Second)
+	peer := newLocalSigner(t)
+	signerKey, peerKey := publicKey(t, signer), publicKey(t, peer)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		encrypt func(ctx context.Context, receiver, plaintext string) (string, error)
+		decrypt func(ctx context.Context, sender, ciphertext string) (string, error)
+	}{
+		{"nip04", signer.NIP04Encrypt, peer.NIP04Decrypt},
+		{"nip44", signer.NIP44Encrypt, peer.NIP44Decrypt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ciphertext, err := tt.encrypt(ctx, peerKey, "secret plans")
+			if err != nil {
+				t.Fatalf("encrypt: %v", err)
+			}
+			plaintext, err := tt.decrypt(ctx, signerKey, ciphertext)
+			if err != nil || plaintext != "secret plans" {
+				t.Fatalf("decrypt = %q, %v", plaintext, err)
+			}
+		})
+	}
+
+	// And back, the peer writing to the bunker's key
+	ciphertext, _ := peer.NIP04Encrypt(ctx, signerKey, "reply")
+	if plaintext, err := signer.NIP04Decrypt(ctx, peerKey, ciphertext); err != nil || plaintext != "reply" {
+		t.Fatalf("nip04 decrypt through the bunker = %q, %v", plaintext, err)
+	}
+	ciphertext, _ = peer.NIP44Encrypt(ctx, signerKey, "reply")
+	if plaintext, err := signer.NIP44Decrypt(ctx, peerKey, ciphertext); err != nil || plaintext != "reply" {
+		t.Fatalf("nip44 decrypt through the bunker = %q, %v", plaintext, err)
+	}
+}
+
+func TestBunkerSignerTimesOut(t *testing.T) {
+	signer := NewBunkerSigner(&fakeBunker{key: newLocalSigner(t), hung: true}, 50*time.Millisecond)
+
+	started := time.Now()
+	err := signer.SignEvent(context.Background(), &nostr.Event{Kind: nostr.KindTextNote})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("sign on a hung bunker = %v, want a deadline error", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("gave up after %s, want about 50ms", elapsed)
+	}
+
+	if _, err := signer.NIP44Encrypt(context.Background(), publicKey(t, newLocalSigner(t)), "x"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("encrypt on a hung bunker = %v, want a deadline error", err)
+	}
+}
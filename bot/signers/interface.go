@@ -0,0 +1,21 @@
+package signers
+
+import (
+	"context"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Signer performs every operation that needs a bot's secret key, so the key
+// itself can live outside the agent
+type Signer interface {
+	GetPublicKey(ctx context.Context) (string, error)
+	SignEvent(ctx context.Context, event *nostr.Event) error
+
+	NIP04Encrypt(ctx context.Context, receiverPublicKey, plaintext string) (string, error)
+	NIP04Decrypt(ctx context.Context, senderPublicKey, ciphertext string) (string, error)
+	NIP44Encrypt(ctx context.Context, receiverPublicKey, plaintext string) (string, error)
+	NIP44Decrypt(ctx context.Context, senderPublicKey, ciphertext string) (string, error)
+
+	Close()
+}
@@ -0,0 +1,111 @@
+package signers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"github.com/nbd-wtf/go-nostr/nip44"
+)
+
+// LocalSigner signs with a secret key held in memory
+type LocalSigner struct {
+	mu sync.Mutex
+
+	secretKey string
+	publicKey string
+
+	sharedSecrets    map[string][]byte   // nip04, by peer public key
+	conversationKeys map[string][32]byte // nip44, by peer public key
+}
+
+// NewLocalSigner creates a signer from a hex secret key
+func NewLocalSigner(secretKey string) (*LocalSigner, error) {
+	publicKey, err := nostr.GetPublicKey(secretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalSigner{
+		secretKey:        secretKey,
+		publicKey:        publicKey,
+		sharedSecrets:    make(map[string][]byte),
+		conversationKeys: make(map[string][32]byte),
+	}, nil
+}
+
+func (s *LocalSigner) GetPublicKey(ctx context.Context) (string, error) {
+	return s.publicKey, nil
+}
+
+func (s *LocalSigner) SignEvent(ctx context.Context, event *nostr.Event) error {
+	return event.Sign(s.secretKey)
+}
+
+func (s *LocalSigner) NIP04Encrypt(ctx context.Context, receiverPublicKey, plaintext string) (string, error) {
+	shared, err := s.sharedSecret(receiverPublicKey)
+	if err != nil {
+		return "", err
+	}
+	return nip04.Encrypt(plaintext, shared)
+}
+
+func (s *LocalSigner) NIP04Decrypt(ctx context.Context, senderPublicKey, ciphertext string) (string, error) {
+	shared, err := s.sharedSecret(senderPublicKey)
+	if err != nil {
+		return "", err
+	}
+	return nip04.Decrypt(ciphertext, shared)
+}
+
+func (s *LocalSigner) NIP44Encrypt(ctx context.Context, receiverPublicKey, plaintext string) (string, error) {
+	key, err := s.conversationKey(receiverPublicKey)
+	if err != nil {
+		return "", err
+	}
+	return nip44.Encrypt(plaintext, key)
+}
+
+func (s *LocalSigner) NIP44Decrypt(ctx context.Context, senderPublicKey, ciphertext string) (string, error) {
+	key, err := s.conversationKey(senderPublicKey)
+	if err != nil {
+		return "", err
+	}
+	return nip44.Decrypt(ciphertext, key)
+}
+
+// Close does nothing for LocalSigner
+func (s *LocalSigner) Close() {}
+
+func (s *LocalSigner) sharedSecret(publicKey string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if shared, found := s.sharedSecrets[publicKey]; found {
+		return shared, nil
+	}
+
+	shared, err := nip04.ComputeSharedSecret(publicKey, s.secretKey)
+	if err != nil {
+		return nil, err
+	}
+	s.sharedSecrets[publicKey] = shared
+	return shared, nil
+}
+
+func (s *LocalSigner) conversationKey(publicKey string) ([32]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, found := s.conversationKeys[publicKey]; found {
+		return key, nil
+	}
+
+	key, err := nip44.GenerateConversationKey(publicKey, s.secretKey)
+	if err != nil {
+		return key, err
+	}
+	s.conversationKeys[publicKey] = key
+	return key, nil
+}
@@ -0,0 +1,20 @@
+package signers
+
+import "agent/core"
+
+// New creates the signer a bot config asks for: a NIP-46 bunker when one is
+// configured, otherwise a local signer holding the resolved secret key
+func New(config core.BotConfig) (Signer, error) {
+	if config.Signer.IsRemote() {
+		return ConnectBunker(config.Signer)
+	}
+
+	sk := config.SecretKey
+	if sk == "" {
+		var err error
+		if sk, err = core.ResolveSecretKey(config); err != nil {
+			return nil, err
+		}
+	}
+	return NewLocalSigner(sk)
+}
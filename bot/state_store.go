@@ -0,0 +1,216 @@
+package bot
+
+import (
+	"agent/core"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// StateKey names the saved state of one program of one bot
+type StateKey struct {
+	Bot     string
+	Program string // Program type, with "#2", "#3"... for repeated types
+}
+
+func (k StateKey) String() string {
+	return k.Bot + "/" + k.Program
+}
+
+// StateStore keeps program state so run budgets and turn positions survive restarts
+type StateStore interface {
+	Load(key StateKey) ([]byte, bool, error)
+	Save(key StateKey, data []byte) error
+	Close() error
+}
+
+// NewStateStore opens the state store of a bot from its config
+func NewStateStore(config core.StateConfig, botName string) (StateStore, error) {
+	base := filepath.Join(config.Directory(), unsafeFileChars.ReplaceAllString(botName, "_"))
+
+	switch config.Backend {
+	case "", "file":
+		return NewFileStateStore(base + ".state.json")
+	case "bolt":
+		return NewBoltStateStore(base + ".state.db")
+	case "memory":
+		return NewMemoryStateStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown state backend: %q", config.Backend)
+	}
+}
+
+// MemoryStateStore keeps state for the lifetime of the process only
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	states map[StateKey][]byte
+}
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{states: make(map[StateKey][]byte)}
+}
+
+func (s *MemoryStateStore) Load(key StateKey) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, found := s.states[key]
+	return data, found, nil
+}
+
+func (s *MemoryStateStore) Save(key StateKey, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *MemoryStateStore) Close() error { return nil }
+
+// FileStateStore keeps the state of one bot's programs in a JSON file
+type FileStateStore struct {
+	mu     sync.Mutex
+	path   string
+	states map[string]json.RawMessage
+}
+
+// NewFileStateStore opens (or lazily creates) the state file at path
+func NewFileStateStore(path string) (*FileStateStore, error) {
+	store := &FileStateStore{
+		path:   path,
+		states: make(map[string]json.RawMessage),
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return store, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read program state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &store.states); err != nil {
+		return nil, fmt.Errorf("failed to parse program state in %s: %w", path, err)
+	}
+	return store, nil
+}
+
+func (s *FileStateStore) Load(key StateKey) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, found := s.states[key.String()]
+	return data, found, nil
+}
+
+// Save records the state and rewrites the file atomically
+func (s *FileStateStore) Save(key StateKey, data []byte) error {
+	if !json.Valid(data) {
+		return fmt.Errorf("state of %s is not valid JSON", key)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[key.String()] = append(json.RawMessage(nil), data...)
+
+	encoded, err := json.MarshalIndent(s.states, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, encoded, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *FileStateStore) Close() error { return nil }
+
+// BoltStateStore keeps program state in an embedded bbolt key-value file,
+// which survives crashes mid-write without rewriting everything on each save
+type BoltStateStore struct {
+	db *bolt.DB
+}
+
+var stateBucket = []byte("programs")
+
+// NewBoltStateStore opens (or creates) the bbolt file at path
+func NewBoltStateStore(path string) (*BoltStateStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	// The file is locked while open, fail instead of hanging when another process holds it
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open program state %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(stateBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStateStore{db: db}, nil
+}
+
+func (s *BoltStateStore) Load(key StateKey) ([]byte, bool, error) {
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket(stateBucket).Get([]byte(key.String())); value != nil {
+			data = append([]byte(nil), value...) // Only valid inside the transaction
+		}
+		return nil
+	})
+	return data, data != nil, err
+}
+
+func (s *BoltStateStore) Save(key StateKey, data []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(stateBucket).Put([]byte(key.String()), data)
+	})
+}
+
+func (s *BoltStateStore) Close() error {
+	return s.db.Close()
+}
+
+// programState is the programs.State of one program, stored as JSON
+type programState struct {
+	store StateStore
+	key   StateKey
+}
+
+func (s *programState) Load(out interface{}) (bool, error) {
+	data, found, err := s.store.Load(s.key)
+	if err != nil || !found {
+		return false, err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return false, fmt.Errorf("failed to parse state of %s: %w", s.key, err)
+	}
+	return true, nil
+}
+
+func (s *programState) Save(value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.store.Save(s.key, data)
+}
@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"agent/bot/programs"
+	"agent/core"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateStoresKeepStateAcrossRestarts(t *testing.T) {
+	tests := []struct {
+		backend string
+		file    string // Expected state file, empty when nothing is written
+		keeps   bool
+	}{
+		{"", "my_bot.state.json", true},
+		{"file", "my_bot.state.json", true},
+		{"bolt", "my_bot.state.db", true},
+		{"memory", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run("backend "+tt.backend, func(t *testing.T) {
+			config := core.StateConfig{Backend: tt.backend, Dir: filepath.Join(t.TempDir(), "state")}
+			chatter, conductor := StateKey{Bot: "my/bot", Program: "ChatterProgram"}, StateKey{Bot: "my/bot", Program: "ConductorProgram#2"}
+
+			store, err := NewStateStore(config, "my/bot")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := (&programState{store: store, key: chatter}).Save(programs.RunState{IsRunning: true, CurrentRunCount: 3}); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+			if err := (&programState{store: store, key: conductor}).Save(programs.RunState{CurrentRunCount: 7}); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+			if err := store.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			if tt.file != "" {
+				if _, err := os.Stat(filepath.Join(config.Dir, tt.file)); err != nil {
+					t.Fatalf("state file: %v", err)
+				}
+			}
+
+			reopened, err := NewStateStore(config, "my/bot")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+
+			var state programs.RunState
+			found, err := (&programState{store: reopened, key: chatter}).Load(&state)
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if found != tt.keeps {
+				t.Fatalf("found state = %v, want %v", found, tt.keeps)
+			}
+			if tt.keeps && (!state.IsRunning || state.CurrentRunCount != 3) {
+				t.Fatalf("restored %+v, want running with 3 runs", state)
+			}
+
+			state = programs.RunState{}
+			(&programState{store: reopened, key: conductor}).Load(&state)
+			if tt.keeps && state.CurrentRunCount != 7 {
+				t.Fatalf("second program restored %+v, want 7 runs", state)
+			}
+		})
+	}
+}
+
+func TestFileStateStoreRejectsBrokenState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.state.json")
+
+	store, err := NewFileStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(StateKey{Bot: "bot", Program: "ChatterProgram"}, []byte("{not json")); err == nil {
+		t.Fatal("saved invalid JSON")
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileStateStore(path); err == nil {
+		t.Fatal("opened a corrupt state file")
+	}
+}
+
+func TestNewStateStoreRejectsUnknownBackends(t *testing.T) {
+	if _, err := NewStateStore(core.StateConfig{Backend: "redis", Dir: t.TempDir()}, "bot"); err == nil {
+		t.Fatal("opened an unknown backend")
+	}
+}
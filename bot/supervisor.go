@@ -0,0 +1,224 @@
+package bot
+
+import (
+	"agent/core"
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// BotState is a step in a bot's connection lifecycle
+type BotState string
+
+const (
+	StateConnecting BotState = "connecting"
+	StateLive       BotState = "live"
+	StateBackoff    BotState = "backoff"
+	StateFailed     BotState = "failed"
+	StateStopped    BotState = "stopped"
+)
+
+// BackoffPolicy controls how a supervisor retries lost connections
+type BackoffPolicy struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	MaxAttempts  int // Consecutive failures before giving up, 0 retries forever
+}
+
+// NewBackoffPolicy builds a policy from config, filling in defaults
+func NewBackoffPolicy(config core.ReconnectConfig) BackoffPolicy {
+	policy := BackoffPolicy{
+		InitialDelay: time.Duration(config.InitialDelay) * time.Second,
+		MaxDelay:     time.Duration(config.MaxDelay) * time.Second,
+		MaxAttempts:  config.MaxAttempts,
+	}
+	if policy.InitialDelay <= 0 {
+		policy.InitialDelay = time.Second
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = 5 * time.Minute
+	}
+	return policy
+}
+
+// Delay returns the jittered wait before the given attempt (starting at 1)
+func (p BackoffPolicy) Delay(attempt int) time.Duration {
+	delay := p.InitialDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+
+	// Equal jitter keeps at least half the delay so bots don't retry in lockstep
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Supervisor keeps a bot connected, reconnecting with exponential backoff
+// instead of letting a lost relay take the process down
+type Supervisor struct {
+	bot    *BaseBot
+	policy BackoffPolicy
+
+	mu       sync.Mutex
+	state    BotState
+	attempts int
+	lastErr  error
+	running  bool
+	done     chan struct{} // Closed when Run returns
+}
+
+// NewSupervisor creates a supervisor for the bot
+func NewSupervisor(bot *BaseBot, policy BackoffPolicy) *Supervisor {
+	return &Supervisor{bot: bot, policy: policy, state: StateStopped, done: make(chan struct{})}
+}
+
+// State returns the current state
+func (s *Supervisor) State() BotState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// starting marks the supervisor as running before the goroutine that calls
+// Run is scheduled, so a Wait in between still waits for it
+func (s *Supervisor) starting() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = true
+}
+
+// Run connects and listens until the bot's context ends or the policy gives up
+func (s *Supervisor) Run(ctx context.Context) {
+	b := s.bot
+
+	s.starting()
+	defer close(s.done)
+
+	for {
+		s.transition(StateConnecting, nil)
+
+		err := b.connectToRelays()
+		if err == nil {
+			s.transition(StateLive, nil)
+			started := time.Now()
+
+			err = b.Listener.StartListening(b)
+			b.IsActiveListener.Store(false)
+
+			// A session that stayed up long enough counts as recovered
+			if time.Since(started) >= s.policy.MaxDelay {
+				s.resetAttempts()
+			}
+			if err == nil {
+				err = fmt.Errorf("subscription ended")
+			}
+		}
+
+		if ctx.Err() != nil {
+			s.transition(StateStopped, nil)
+			return
+		}
+
+		attempt := s.failed()
+		if s.policy.MaxAttempts > 0 && attempt > s.policy.MaxAttempts {
+			log.Printf("💀 [%s] giving up after %d attempts: %v", b.Config.Name, s.policy.MaxAttempts, err)
+			s.transition(StateFailed, err)
+			return
+		}
+
+		delay := s.policy.Delay(attempt)
+		log.Printf("⏳ [%s] %v, reconnecting in %s (attempt %d)", b.Config.Name, err, delay.Round(time.Millisecond), attempt)
+		s.transition(StateBackoff, err)
+
+		select {
+		case <-ctx.Done():
+			s.transition(StateStopped, nil)
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Wait blocks until Run has returned or the context ends
+func (s *Supervisor) Wait(ctx context.Context) error {
+	s.mu.Lock()
+	running := s.running
+	s.mu.Unlock()
+
+	if !running {
+		return nil
+	}
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("listener did not stop: %w", ctx.Err())
+	}
+}
+
+// LastError returns why the bot last left the live state
+func (s *Supervisor) LastError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastErr
+}
+
+func (s *Supervisor) failed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	return s.attempts
+}
+
+func (s *Supervisor) resetAttempts() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts = 0
+}
+
+// transition records the new state and announces entering or leaving live, and
+// stopping for good, on the bus
+func (s *Supervisor) transition(state BotState, reason error) {
+	s.mu.Lock()
+	previous := s.state
+	s.state = state
+	if reason != nil {
+		s.lastErr = reason
+	}
+	s.mu.Unlock()
+
+	if previous == state {
+		return
+	}
+
+	log.Printf("🚦 [%s] %s → %s", s.bot.Config.Name, previous, state)
+
+	switch {
+	case state == StateLive:
+		s.publish(core.BotStartedEvent, state, reason)
+	case previous == StateLive || state == StateStopped:
+		s.publish(core.BotStoppedEvent, state, reason)
+	}
+}
+
+func (s *Supervisor) publish(eventType core.EventType, state BotState, reason error) {
+	metadata := ""
+	if reason != nil {
+		metadata = reason.Error()
+	}
+
+	s.bot.EventBus.Publish(eventType, &core.BusMessage{
+		SenderPublicKey: s.bot.PublicKey,
+		Payload: core.ContentStructure{
+			Kind:     "bot_state",
+			Text:     string(state),
+			Metadata: metadata,
+		},
+		Timestamp: time.Now().Unix(),
+	})
+}
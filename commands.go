@@ -0,0 +1,89 @@
+package main
+
+import (
+	"agent/bot"
+	"agent/bot/programs"
+	"agent/core"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// 🧰 Run a subcommand, reporting whether one matched
+func runCommand(name string, args []string) bool {
+	switch name {
+	case "list-components":
+		listComponents()
+	case "validate":
+		os.Exit(validate(args))
+	default:
+		return false
+	}
+	return true
+}
+
+// 📋 Print every registered component by kind
+func listComponents() {
+	components := bot.Components()
+	components["programs"] = programs.Types()
+
+	kinds := make([]string, 0, len(components))
+	for kind := range components {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		fmt.Printf("%s:\n", kind)
+		for _, name := range components[kind] {
+			fmt.Printf("  - %s\n", name)
+		}
+	}
+}
+
+// 🔍 Validate config files and report every problem found
+func validate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	configFile := flags.String("config", "", "YAML configuration file, directory of files or glob to validate")
+	flags.Parse(args)
+
+	paths := flags.Args()
+	if *configFile != "" {
+		paths = append([]string{*configFile}, paths...)
+	}
+
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "❌ No configuration file provided. Use 'agent validate --config=your_bot.yaml'")
+		return 2
+	}
+
+	status := 0
+	for _, path := range paths {
+		botConfigs, err := core.LoadBotConfigs(path)
+		if err == nil {
+			err = core.ValidateBotConfigs(botConfigs, knownComponents())
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s\n%v\n", path, err)
+			status = 1
+			continue
+		}
+
+		fmt.Printf("✅ %s is valid (%d bots)\n", path, len(botConfigs.Bots))
+	}
+	return status
+}
+
+// 🗂️ Names every config may refer to
+func knownComponents() core.KnownComponents {
+	components := bot.Components()
+	return core.KnownComponents{
+		Listeners:  components["listeners"],
+		Publishers: components["publishers"],
+		Handlers:   components["handlers"],
+		EventTypes: components["event_types"],
+		Programs:   programs.Types(),
+	}
+}
@@ -1,38 +1,133 @@
 package core
 
 import (
-	"log"
-	"os"
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // BotConfig defines the structure for each bot
 type BotConfig struct {
-	Name          string        `yaml:"name"`
-	Aliases       []string      `yaml:"aliases"`
-	RelayURL      string        `yaml:"relay_url"`
-	Nsec          string        `yaml:"nsec"`
-	ChannelID     string        `yaml:"channel_id"`
-	Listener      string        `yaml:"listener"`
-	Publisher     string        `yaml:"publisher"`
-	Handler       string        `yaml:"handler"`
-	EventType     string        `yaml:"event_type"`
-	ProgramConfig ProgramConfig `yaml:"program"`
+	Name          string          `yaml:"name"`
+	Aliases       []string        `yaml:"aliases"`
+	RelayURL      string          `yaml:"relay_url"`
+	RelayURLs     []RelayConfig   `yaml:"relay_urls"`
+	Nsec          string          `yaml:"nsec"`
+	Npub          string          `yaml:"npub"`
+	ChannelID     string          `yaml:"channel_id"`
+	Listener      string          `yaml:"listener"`
+	Publisher     string          `yaml:"publisher"`
+	Handler       string          `yaml:"handler"`
+	EventType     string          `yaml:"event_type"`
+	ProgramConfig ProgramConfig   `yaml:"program"` // Deprecated: use Programs
+	Programs      []ProgramConfig `yaml:"programs"`
+
+	NsecEnv                string       `yaml:"nsec_env"`
+	NsecFile               string       `yaml:"nsec_file"`
+	Ncryptsec              string       `yaml:"ncryptsec"`
+	NcryptsecPassphraseEnv string       `yaml:"ncryptsec_passphrase_env"`
+	Signer                 SignerConfig `yaml:"signer"`
+
+	Reconnect ReconnectConfig `yaml:"reconnect"`
+	Cursor    CursorConfig    `yaml:"cursor"`
+	Dedup     DedupConfig     `yaml:"dedup"`
+	State     StateConfig     `yaml:"state"`
+
+	MailboxSize int            `yaml:"mailbox_size"` // Messages queued for programs before new ones are dropped, 100 by default
+	HistorySize int            `yaml:"history_size"` // Program runs kept for the admin API, 100 by default
+	EventBus    EventBusConfig `yaml:"event_bus"`
+	Bridge      BridgeConfig   `yaml:"bridge"`
+
+	ListenerOptions  ComponentOptions `yaml:"listener_options"`
+	PublisherOptions ComponentOptions `yaml:"publisher_options"`
+	HandlerOptions   ComponentOptions `yaml:"handler_options"`
+
+	SecretKey string `yaml:"-"` // Hex secret key resolved from one of the key sources, empty for remote signers
+	keyErr    error  // Why the secret key could not be resolved
 }
 
 // BotConfigs is a wrapper to handle multiple bots
 type BotConfigs struct {
 	Bots []BotConfig `yaml:"bots"`
+
+	source   string                // File, directory or glob the configs were loaded from
+	origins  []botOrigin           // Where each bot was defined, used to locate errors
+	included map[*yaml.Node]string // Values merged in from fragments, by fragment file
+}
+
+// botOrigin points at the YAML mapping a bot was decoded from
+type botOrigin struct {
+	file string
+	node *yaml.Node
 }
 
+// ProgramConfig describes a single program attached to a bot
 type ProgramConfig struct {
-	MaxRunCount   int          `yaml:"max_run_count"`
-	ResponseDelay int          `yaml:"response_delay"`
-	WorkerConfig  WorkerConfig `yaml:"worker"`
-	HubConfig     HubConfig    `yaml:"hub"`
-	Pattern       string       `yaml:"pattern"`
-	CallbackUrl   string       `yaml:"callback_url"`
+	Type          string         `yaml:"type"`
+	Leader        bool           `yaml:"leader"`
+	MaxRunCount   int            `yaml:"max_run_count"`
+	ResponseDelay int            `yaml:"response_delay"`
+	WorkerConfig  WorkerConfig   `yaml:"worker"`
+	HubConfig     HubConfig      `yaml:"hub"`
+	Pattern       string         `yaml:"pattern"`
+	CallbackUrl   string         `yaml:"callback_url"`
+	Schedule      ScheduleConfig `yaml:"schedule"` // When a ScheduledProgram fires and what it does
+
+	Workers   map[string]WorkerBackendConfig `yaml:"workers"`   // Command verbs a ConductorProgram hands to worker backends
+	Operators []string                       `yaml:"operators"` // Public keys that may ask a ConductorProgram about any crawler job ID
+}
+
+// ReservedWorkerVerbs are the ConductorProgram's own commands, which cannot route to a backend
+var ReservedWorkerVerbs = []string{"jobs", "queue", "cancel", "status"}
+
+// WorkerBackendConfig routes one command verb of a ConductorProgram to a
+// worker service. The embedded worker settings give the url of an http
+// backend, or the address, tls, retry, breaker and health_interval of a
+// crawler backend. Concurrency and queueing are shared, see WorkerConfig
+type WorkerBackendConfig struct {
+	Kind    string            `yaml:"kind"`    // "crawler" (gRPC), "http" (JSON job endpoint) or "exec" (local executable)
+	Args    []string          `yaml:"args"`    // Arguments after the verb, "name?" is optional and "name..." takes the rest of the message
+	Timeout int               `yaml:"timeout"` // Seconds a job may run, worker.job_timeout by default
+	Reply   string            `yaml:"reply"`   // Go template of the reply with .Verb, .Job, .Args, .Output and .JSON
+	Method  string            `yaml:"method"`  // HTTP method, POST by default
+	Headers map[string]string `yaml:"headers"` // Extra HTTP headers, e.g. an API key
+	Command []string          `yaml:"command"` // Executable and its arguments, each a Go template over the named arguments
+
+	WorkerConfig `yaml:",inline"`
+}
+
+// Deadline returns how long a job of this backend may run, falling back to the shared deadline
+func (c WorkerBackendConfig) Deadline(shared WorkerConfig) time.Duration {
+	if c.Timeout <= 0 {
+		return shared.JobDeadline()
+	}
+	return time.Duration(c.Timeout) * time.Second
+}
+
+// ScheduleConfig makes a ScheduledProgram fire on a cron expression or a
+// fixed interval, either publishing a message or feeding a trigger text to
+// the bot's other programs
+type ScheduleConfig struct {
+	Cron     string `yaml:"cron"`     // "minute hour day month weekday", e.g. "0 8 * * *", or @daily, @hourly...
+	Every    int    `yaml:"every"`    // Seconds between runs, instead of cron
+	Timezone string `yaml:"timezone"` // IANA zone the cron expression is read in, UTC by default
+	CatchUp  string `yaml:"catch_up"` // Runs missed while stopped: "skip" (default), "once" or "all"
+
+	Message  string `yaml:"message"`  // Text/template of the published message, with .Bot, .Time and .Run
+	Receiver string `yaml:"receiver"` // npub or hex key to DM, for DM publishers
+	Trigger  string `yaml:"trigger"`  // Text passed to the bot's programs instead of publishing, e.g. "🧮"
+}
+
+// Location returns the time zone of the cron expression
+func (c ScheduleConfig) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(c.Timezone)
 }
 
 type HubConfig struct {
@@ -40,23 +135,388 @@ type HubConfig struct {
 }
 
 type WorkerConfig struct {
-	Url     string `yaml:"url"`
-	Address string `yaml:"address"`
+	Url         string `yaml:"url"`
+	Address     string `yaml:"address"`
+	Concurrency int    `yaml:"concurrency"` // Jobs running at once, 2 by default
+	QueueSize   int    `yaml:"queue_size"`  // Jobs waiting for a free worker, 20 by default
+	JobTimeout  int    `yaml:"job_timeout"` // Seconds a job may run before it is cancelled, 600 by default
+
+	TLS            WorkerTLSConfig `yaml:"tls"`
+	Retry          RetryConfig     `yaml:"retry"`
+	Breaker        BreakerConfig   `yaml:"breaker"`
+	HealthInterval int             `yaml:"health_interval"` // Seconds between health probes, 30 by default, negative disables them
+}
+
+// WorkerTLSConfig secures the connection to the worker. Setting any file turns TLS on
+type WorkerTLSConfig struct {
+	Enabled    bool   `yaml:"enabled"`     // Use TLS with the system roots
+	CAFile     string `yaml:"ca_file"`     // PEM roots the worker's certificate is verified against
+	CertFile   string `yaml:"cert_file"`   // Client certificate for mutual TLS
+	KeyFile    string `yaml:"key_file"`    // Key of the client certificate
+	ServerName string `yaml:"server_name"` // Name expected in the worker's certificate, the address host by default
+}
+
+// IsEnabled reports whether the worker is reached over TLS
+func (c WorkerTLSConfig) IsEnabled() bool {
+	return c.Enabled || c.CAFile != "" || c.CertFile != "" || c.KeyFile != ""
+}
+
+// RetryConfig controls how a failed worker call is retried
+type RetryConfig struct {
+	MaxAttempts  int `yaml:"max_attempts"`  // Tries per call including the first, 3 by default
+	InitialDelay int `yaml:"initial_delay"` // Seconds before the first retry, 1 by default
+	MaxDelay     int `yaml:"max_delay"`     // Upper bound in seconds for the doubling delay, 10 by default
+}
+
+// Attempts returns the tries per call, falling back to the default
+func (c RetryConfig) Attempts() int {
+	if c.MaxAttempts <= 0 {
+		return 3
+	}
+	return c.MaxAttempts
+}
+
+// Delays returns the first and the largest delay between tries, falling back to the defaults
+func (c RetryConfig) Delays() (time.Duration, time.Duration) {
+	initial, max := time.Second, 10*time.Second
+	if c.InitialDelay > 0 {
+		initial = time.Duration(c.InitialDelay) * time.Second
+	}
+	if c.MaxDelay > 0 {
+		max = time.Duration(c.MaxDelay) * time.Second
+	}
+	return initial, max
+}
+
+// BreakerConfig controls when calls to the worker stop being attempted
+type BreakerConfig struct {
+	Failures int `yaml:"failures"` // Consecutive failed calls that open the breaker, 5 by default
+	Cooldown int `yaml:"cooldown"` // Seconds the breaker stays open before letting a probe call through, 30 by default
+}
+
+// Threshold returns the failures that open the breaker, falling back to the default
+func (c BreakerConfig) Threshold() int {
+	if c.Failures <= 0 {
+		return 5
+	}
+	return c.Failures
+}
+
+// CooldownDuration returns how long the breaker stays open, falling back to the default
+func (c BreakerConfig) CooldownDuration() time.Duration {
+	if c.Cooldown <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.Cooldown) * time.Second
+}
+
+// Workers returns how many jobs may run at once, falling back to the default
+func (c WorkerConfig) Workers() int {
+	if c.Concurrency <= 0 {
+		return 2
+	}
+	return c.Concurrency
+}
+
+// QueueCapacity returns how many jobs may wait, falling back to the default
+func (c WorkerConfig) QueueCapacity() int {
+	if c.QueueSize <= 0 {
+		return 20
+	}
+	return c.QueueSize
+}
+
+// JobDeadline returns how long a job may run, falling back to ten minutes
+func (c WorkerConfig) JobDeadline() time.Duration {
+	if c.JobTimeout <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(c.JobTimeout) * time.Second
+}
+
+// HealthEvery returns the time between health probes, zero when they are disabled
+func (c WorkerConfig) HealthEvery() time.Duration {
+	switch {
+	case c.HealthInterval < 0:
+		return 0
+	case c.HealthInterval == 0:
+		return 30 * time.Second
+	}
+	return time.Duration(c.HealthInterval) * time.Second
+}
+
+// RelayConfig is a relay with its roles. Both roles are on unless disabled
+type RelayConfig struct {
+	URL   string `yaml:"url"`
+	Read  *bool  `yaml:"read"`
+	Write *bool  `yaml:"write"`
+}
+
+// CanRead reports whether the bot subscribes through this relay
+func (r RelayConfig) CanRead() bool {
+	return r.Read == nil || *r.Read
+}
+
+// CanWrite reports whether the bot publishes through this relay
+func (r RelayConfig) CanWrite() bool {
+	return r.Write == nil || *r.Write
+}
+
+// ReconnectConfig controls how a bot retries lost relay connections
+type ReconnectConfig struct {
+	InitialDelay int `yaml:"initial_delay"` // Seconds before the first retry
+	MaxDelay     int `yaml:"max_delay"`     // Upper bound in seconds for the doubling delay
+	MaxAttempts  int `yaml:"max_attempts"`  // Consecutive failures before giving up, 0 retries forever
+}
+
+// CursorConfig controls how listeners remember and catch up on missed events
+type CursorConfig struct {
+	Dir        string `yaml:"dir"`          // Directory of the per-bot cursor files, "data/cursors" by default
+	MaxCatchUp int    `yaml:"max_catch_up"` // Oldest backlog in seconds replayed after a restart, one day by default
+	Disabled   bool   `yaml:"disabled"`     // Keep cursors in memory only
+}
+
+// Directory returns the cursor directory, falling back to the default
+func (c CursorConfig) Directory() string {
+	if c.Dir == "" {
+		return "data/cursors"
+	}
+	return c.Dir
 }
 
-// LoadBotConfigs loads the bot configurations from a YAML file
-func LoadBotConfigs(path string) (*BotConfigs, error) {
-	data, err := os.ReadFile(path)
+// MaxCatchUpDuration returns the catch-up window, falling back to one day
+func (c CursorConfig) MaxCatchUpDuration() time.Duration {
+	if c.MaxCatchUp <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(c.MaxCatchUp) * time.Second
+}
+
+// DedupConfig controls how listeners reject duplicate, replayed or forged events
+type DedupConfig struct {
+	Capacity  int    `yaml:"capacity"`   // Event IDs remembered, 10000 by default
+	Persist   bool   `yaml:"persist"`    // Keep remembered IDs across restarts
+	Dir       string `yaml:"dir"`        // Directory of the per-bot ID logs, "data/dedup" by default
+	MaxPast   int    `yaml:"max_past"`   // Oldest created_at accepted in seconds, 0 accepts any age
+	MaxFuture int    `yaml:"max_future"` // Furthest created_at in the future accepted in seconds, 300 by default
+}
+
+// CapacityOrDefault returns the LRU capacity, falling back to the default
+func (c DedupConfig) CapacityOrDefault() int {
+	if c.Capacity <= 0 {
+		return 10000
+	}
+	return c.Capacity
+}
+
+// Directory returns the ID log directory, falling back to the default
+func (c DedupConfig) Directory() string {
+	if c.Dir == "" {
+		return "data/dedup"
+	}
+	return c.Dir
+}
+
+// MaxFutureDuration returns the accepted clock skew, falling back to five minutes
+func (c DedupConfig) MaxFutureDuration() time.Duration {
+	if c.MaxFuture <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(c.MaxFuture) * time.Second
+}
+
+// StateConfig controls where programs keep their progress across restarts
+type StateConfig struct {
+	Backend string `yaml:"backend"` // "file" (default) keeps a JSON file per bot, "bolt" an embedded key-value file, "memory" nothing across restarts
+	Dir     string `yaml:"dir"`     // Directory of the per-bot state files, "data/state" by default
+}
+
+// Directory returns the state directory, falling back to the default
+func (c StateConfig) Directory() string {
+	if c.Dir == "" {
+		return "data/state"
+	}
+	return c.Dir
+}
+
+// EventBusConfig controls how a bot's EventBus delivers messages to handlers
+type EventBusConfig struct {
+	Mode         string `yaml:"mode"`          // "ordered" (default) keeps messages with the same key in order, "unordered" spreads them over every worker
+	OrderBy      string `yaml:"order_by"`      // Ordering key: "sender" (default) or "channel"
+	Workers      int    `yaml:"workers"`       // Handler goroutines, 8 by default
+	QueueSize    int    `yaml:"queue_size"`    // Messages queued per worker, 256 by default
+	Overflow     string `yaml:"overflow"`      // When a queue is full: "block" (default) or "drop"
+	BlockTimeout int    `yaml:"block_timeout"` // Seconds a blocked publish waits before dropping, 5 by default
+}
+
+// BridgeConfig forwards EventBus messages between agent processes
+type BridgeConfig struct {
+	Listen  string        `yaml:"listen"`  // Address peers connect to: "unix:///path/to.sock" or "tcp://host:port"
+	Connect []string      `yaml:"connect"` // Peer addresses to dial, in the same form
+	Routes  []BridgeRoute `yaml:"routes"`  // Event types to forward and in which direction
+	Secret  string        `yaml:"secret"`  // Shared secret peers prove in the handshake, required to listen on a non-loopback TCP address
+}
+
+// BridgeRoute selects an event type to forward
+type BridgeRoute struct {
+	Event     string `yaml:"event"`     // Event type, "*" for all or a prefix such as "dm_*"
+	Direction string `yaml:"direction"` // "out" sends local messages to peers, "in" publishes peer messages locally, "both" (default) does both
+	Publish   bool   `yaml:"publish"`   // Let peer messages of this event reach the bot's publisher, and so the relays
+}
+
+// Enabled reports whether the bot bridges its EventBus to other processes
+func (c BridgeConfig) Enabled() bool {
+	return c.Listen != "" || len(c.Connect) > 0
+}
+
+// ParseBridgeAddress splits a "unix://" or "tcp://" address into the network
+// and address expected by net.Dial and net.Listen
+func ParseBridgeAddress(address string) (network, addr string, err error) {
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		network, addr = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "tcp://"):
+		network, addr = "tcp", strings.TrimPrefix(address, "tcp://")
+	default:
+		return "", "", fmt.Errorf("bridge address %q must start with unix:// or tcp://", address)
+	}
+	if addr == "" {
+		return "", "", fmt.Errorf("bridge address %q has no path or host", address)
+	}
+	return network, addr, nil
+}
+
+// ExposedBridgeAddress reports whether a bridge address accepts peers from
+// other hosts, i.e. TCP on anything but a loopback address
+func ExposedBridgeAddress(address string) bool {
+	network, addr, err := ParseBridgeAddress(address)
+	if err != nil || network != "tcp" {
+		return false
+	}
+	host, _, err := net.SplitHostPort(addr)
 	if err != nil {
-		log.Fatalf("❌ Failed to read config file: %v", err)
-		return nil, err
+		return true
+	}
+	if host == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || !ip.IsLoopback()
+}
+
+// SignerConfig points a bot at a NIP-46 remote signer instead of a local key
+type SignerConfig struct {
+	Bunker        string `yaml:"bunker"`          // bunker:// URL of the remote signer
+	BunkerEnv     string `yaml:"bunker_env"`      // Environment variable holding the bunker URL
+	ClientNsecEnv string `yaml:"client_nsec_env"` // Key used to talk to the bunker, random when omitted
+	Timeout       int    `yaml:"timeout"`         // Seconds to wait for each signer request
+}
+
+// IsRemote reports whether a remote signer is configured
+func (c SignerConfig) IsRemote() bool {
+	return c.Bunker != "" || c.BunkerEnv != ""
+}
+
+// TimeoutDuration returns the per-request timeout, defaulting to 30 seconds
+func (c SignerConfig) TimeoutDuration() time.Duration {
+	if c.Timeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.Timeout) * time.Second
+}
+
+// ComponentOptions keeps the raw YAML settings of a listener, publisher or
+// handler so that each component factory can decode its own typed options
+type ComponentOptions struct {
+	node *yaml.Node
+}
+
+// UnmarshalYAML stores the node for later decoding
+func (o *ComponentOptions) UnmarshalYAML(node *yaml.Node) error {
+	o.node = node
+	return nil
+}
+
+// MarshalYAML writes the stored node back out unchanged
+func (o ComponentOptions) MarshalYAML() (interface{}, error) {
+	if o.node == nil {
+		return nil, nil
+	}
+	return o.node, nil
+}
+
+// Decode unmarshals the options into the given struct. Missing options leave it untouched
+func (o ComponentOptions) Decode(out interface{}) error {
+	if o.node == nil {
+		return nil
+	}
+	return o.node.Decode(out)
+}
+
+// Equal reports whether two configs describe the same bot, including the
+// resolved secret key so a rotated key file counts as a change
+func (c BotConfig) Equal(other BotConfig) bool {
+	if c.SecretKey != other.SecretKey {
+		return false
 	}
 
-	var botConfigs BotConfigs
-	if err := yaml.Unmarshal(data, &botConfigs); err != nil {
-		log.Fatalf("❌ Failed to parse YAML config: %v", err)
-		return nil, err
+	a, errA := yaml.Marshal(c)
+	b, errB := yaml.Marshal(other)
+	return errA == nil && errB == nil && bytes.Equal(a, b)
+}
+
+// MailboxCapacity returns the program queue size, falling back to the default
+func (c BotConfig) MailboxCapacity() int {
+	if c.MailboxSize <= 0 {
+		return 100
 	}
+	return c.MailboxSize
+}
 
-	return &botConfigs, nil
+// HistoryCapacity returns how many program runs to keep, falling back to the default
+func (c BotConfig) HistoryCapacity() int {
+	if c.HistorySize <= 0 {
+		return 100
+	}
+	return c.HistorySize
+}
+
+// Relays returns every relay of the bot, with the legacy `relay_url` used for
+// both reading and writing
+func (c BotConfig) Relays() []RelayConfig {
+	var relays []RelayConfig
+	if c.RelayURL != "" {
+		relays = append(relays, RelayConfig{URL: c.RelayURL})
+	}
+	return append(relays, c.RelayURLs...)
+}
+
+// LegacyProgramTypes are the programs bots of these names ran before
+// programs were declared with a type
+var LegacyProgramTypes = map[string]string{
+	"Yin":        "ChatterProgram",
+	"Yang":       "ResponderProgram",
+	"HypeWizard": "ConductorProgram",
+	"Telegram":   "CallbackProgram",
+}
+
+// migrateLegacyProgram gives a bot that declares no typed program the type
+// its name used to imply, reporting whether it did
+func (c *BotConfig) migrateLegacyProgram() bool {
+	legacy, found := LegacyProgramTypes[c.Name]
+	if !found || c.ProgramConfig.Type != "" || len(c.Programs) > 0 {
+		return false
+	}
+	c.ProgramConfig.Type = legacy
+	return true
+}
+
+// ProgramList returns every program configured for the bot, including the
+// legacy single `program:` entry when it declares a type
+func (c BotConfig) ProgramList() []ProgramConfig {
+	list := append([]ProgramConfig{}, c.Programs...)
+	if c.ProgramConfig.Type != "" {
+		list = append(list, c.ProgramConfig)
+	}
+	return list
 }
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed five-field cron expression: minute, hour, day of
+// month, month and day of week. Fields accept *, numbers, ranges (1-5),
+// lists (1,15) and steps (*/10, 8-18/2)
+type CronSchedule struct {
+	minute, hour, day, month, weekday uint64 // Bit n is set when value n matches
+
+	anyDay, anyWeekday bool // Day of month or week left as *, see matchesDay
+}
+
+var cronMacros = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+// ParseCron parses a cron expression or one of @yearly, @monthly, @weekly,
+// @daily and @hourly
+func ParseCron(expression string) (*CronSchedule, error) {
+	if macro, found := cronMacros[strings.TrimSpace(expression)]; found {
+		expression = macro
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, has %d", expression, len(fields))
+	}
+
+	var (
+		schedule CronSchedule
+		err      error
+	)
+	bounds := []struct {
+		name     string
+		min, max int
+		bits     *uint64
+	}{
+		{"minute", 0, 59, &schedule.minute},
+		{"hour", 0, 23, &schedule.hour},
+		{"day of month", 1, 31, &schedule.day},
+		{"month", 1, 12, &schedule.month},
+		{"day of week", 0, 7, &schedule.weekday},
+	}
+	for i, field := range fields {
+		b := bounds[i]
+		if *b.bits, err = parseCronField(field, b.min, b.max); err != nil {
+			return nil, fmt.Errorf("cron %s %q: %w", b.name, field, err)
+		}
+	}
+
+	// Sunday is both 0 and 7
+	if schedule.weekday&(1<<7) != 0 {
+		schedule.weekday |= 1
+	}
+	// Like cron, a day field starting with * is unrestricted even with a step
+	schedule.anyDay = strings.HasPrefix(fields[2], "*")
+	schedule.anyWeekday = strings.HasPrefix(fields[4], "*")
+	return &schedule, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		span, step := part, 1
+		if before, after, found := strings.Cut(part, "/"); found {
+			n, err := strconv.Atoi(after)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", after)
+			}
+			span, step = before, n
+		}
+
+		low, high := min, max
+		if span != "*" {
+			from, to, isRange := strings.Cut(span, "-")
+			var err error
+			if low, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("invalid value %q", from)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("invalid value %q", to)
+				}
+			} else if step > 1 {
+				high = max // "5/15" means from 5 to the end
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%d-%d is outside %d-%d", low, high, min, max)
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time after t that matches the schedule, in t's location
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// Every combination repeats within a few years, give up after that
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay follows cron: when both day fields are restricted, either one may match
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	day := s.day&(1<<uint(t.Day())) != 0
+	weekday := s.weekday&(1<<uint(t.Weekday())) != 0
+
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone data")
+	}
+	at := func(year int, month time.Month, day, hour, minute int, loc *time.Location) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		from       time.Time
+		want       []time.Time // The next fires in order, none when it never fires
+	}{
+		{
+			name:       "day of month or week when both are restricted",
+			expression: "0 0 13 * 5",
+			from:       at(2024, 10, 5, 0, 0, time.UTC), // A Saturday
+			want:       []time.Time{at(2024, 10, 11, 0, 0, time.UTC), at(2024, 10, 13, 0, 0, time.UTC), at(2024, 10, 18, 0, 0, time.UTC)},
+		},
+		{
+			name:       "a day of month step leaves the day unrestricted",
+			expression: "0 0 */10 * 1",
+			from:       at(2024, 1, 2, 0, 0, time.UTC),
+			want:       []time.Time{at(2024, 3, 11, 0, 0, time.UTC), at(2024, 4, 1, 0, 0, time.UTC)},
+		},
+		{
+			name:       "sunday written as 7",
+			expression: "0 9 * * 7",
+			from:       at(2024, 9, 6, 12, 0, time.UTC), // A Friday
+			want:       []time.Time{at(2024, 9, 8, 9, 0, time.UTC), at(2024, 9, 15, 9, 0, time.UTC)},
+		},
+		{
+			name:       "minute steps",
+			expression: "*/15 * * * *",
+			from:       at(2024, 9, 6, 10, 7, time.UTC),
+			want:       []time.Time{at(2024, 9, 6, 10, 15, time.UTC), at(2024, 9, 6, 10, 30, time.UTC), at(2024, 9, 6, 10, 45, time.UTC)},
+		},
+		{
+			name:       "hour range with a step",
+			expression: "0 8-18/5 * * *",
+			from:       at(2024, 9, 6, 8, 0, time.UTC),
+			want:       []time.Time{at(2024, 9, 6, 13, 0, time.UTC), at(2024, 9, 6, 18, 0, time.UTC), at(2024, 9, 7, 8, 0, time.UTC)},
+		},
+		{
+			name:       "a time skipped by the DST change fires the next day",
+			expression: "30 2 * * *",
+			from:       at(2024, 3, 30, 12, 0, berlin),
+			want:       []time.Time{at(2024, 4, 1, 2, 30, berlin)},
+		},
+		{
+			name:       "never matches",
+			expression: "0 0 30 2 *",
+			from:       at(2024, 1, 1, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			next := schedule.Next(tt.from)
+			if len(tt.want) == 0 {
+				if !next.IsZero() {
+					t.Fatalf("next = %s, want the zero time", next)
+				}
+				return
+			}
+			for i, want := range tt.want {
+				if !next.Equal(want) {
+					t.Fatalf("fire %d = %s, want %s", i, next, want)
+				}
+				next = schedule.Next(next)
+			}
+		})
+	}
+}
+
+func TestParseCronRejects(t *testing.T) {
+	for _, expression := range []string{"* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "x * * * *"} {
+		if _, err := ParseCron(expression); err == nil {
+			t.Errorf("ParseCron(%q) accepted", expression)
+		}
+	}
+}
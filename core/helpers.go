@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"log"
 	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
 )
 
 // 🛠️ Convert structured content to JSON string
@@ -37,3 +40,22 @@ func ExtractMention(content string) string {
 	}
 	return ""
 }
+
+// 🛠️ Shorten an ID to its last characters for logs
+func ShortID(id string) string {
+	if len(id) <= 3 {
+		return id
+	}
+	return id[len(id)-3:]
+}
+
+// HexPublicKey returns the hex form of a public key written as hex or as an npub
+func HexPublicKey(key string) (string, bool) {
+	if nostr.IsValidPublicKey(key) {
+		return key, true
+	}
+	if prefix, value, err := nip19.Decode(key); err == nil && prefix == "npub" {
+		return value.(string), true
+	}
+	return "", false
+}
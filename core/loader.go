@@ -0,0 +1,309 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeKey names the mapping key that pulls shared fragments into a config
+const includeKey = "include"
+
+// envPattern matches `${VAR}`, `${VAR:-default}` and the `$$` escape
+var envPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// LoadBotConfigs loads the bot configurations from a YAML file, every
+// .yaml/.yml file of a directory or every file matching a glob.
+//
+// Any mapping may `include:` one or more fragment files, relative to the file
+// including them, whose keys fill in what the mapping does not set. String
+// values may refer to the environment as `${VAR}` or `${VAR:-default}`
+func LoadBotConfigs(path string) (*BotConfigs, error) {
+	files, err := configFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	botConfigs := BotConfigs{source: path, included: make(map[*yaml.Node]string)}
+	for _, file := range files {
+		if err := botConfigs.loadFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	// 🔑 Resolve local secret keys, leaving failures for validation to report
+	for i := range botConfigs.Bots {
+		config := &botConfigs.Bots[i]
+		if config.migrateLegacyProgram() {
+			log.Printf("⚠️ [%s] has no typed program, attaching %s by its name. This is deprecated, declare it under programs: with a type", config.Name, config.ProgramConfig.Type)
+		}
+		if !config.Signer.IsRemote() {
+			config.SecretKey, config.keyErr = ResolveSecretKey(*config)
+		}
+	}
+
+	return &botConfigs, nil
+}
+
+// configFiles expands a config path into the files to load, in a stable order
+func configFiles(path string) ([]string, error) {
+	if strings.ContainsAny(path, "*?[") {
+		files, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config pattern %s: %w", path, err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no config files match %s", path)
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory: %w", err)
+	}
+
+	// Subdirectories are skipped so they can hold included fragments
+	var files []string
+	for _, entry := range entries {
+		if ext := filepath.Ext(entry.Name()); !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .yaml or .yml files in %s", path)
+	}
+	return files, nil
+}
+
+// loadFile appends the bots of a single config file
+func (c *BotConfigs) loadFile(path string) error {
+	document, err := c.loadDocument(path, nil)
+	if err != nil {
+		return err
+	}
+	if document.Kind == 0 {
+		return nil
+	}
+
+	var file struct {
+		Bots []BotConfig `yaml:"bots"`
+	}
+	if err := document.Decode(&file); err != nil {
+		return fmt.Errorf("failed to parse YAML config %s: %w", path, err)
+	}
+
+	nodes := mappingValue(document.Content[0], "bots")
+	if fragment, found := c.included[nodes]; found {
+		path = fragment // The bots themselves came from an include
+	}
+	for i, bot := range file.Bots {
+		origin := botOrigin{file: path}
+		if nodes != nil && i < len(nodes.Content) {
+			origin.node = nodes.Content[i]
+		}
+		c.Bots = append(c.Bots, bot)
+		c.origins = append(c.origins, origin)
+	}
+	return nil
+}
+
+// loadDocument parses a YAML file, expands environment variables and merges
+// its includes. including holds the files being loaded, to detect cycles
+func (c *BotConfigs) loadDocument(path string, including []string) (*yaml.Node, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(including, absolute) {
+		return nil, fmt.Errorf("include cycle: %s", strings.Join(append(including, absolute), " → "))
+	}
+	including = append(including, absolute)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML config %s: %w", path, err)
+	}
+	if document.Kind == 0 {
+		return &document, nil
+	}
+
+	if err := expandEnv(&document, path); err != nil {
+		return nil, err
+	}
+	if err := c.resolveIncludes(&document, path, including); err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// expandEnv replaces environment references in every scalar value
+func expandEnv(node *yaml.Node, path string) error {
+	if node.Kind == yaml.ScalarNode {
+		if !strings.Contains(node.Value, "$") {
+			return nil
+		}
+
+		var missing []string
+		value := envPattern.ReplaceAllStringFunc(node.Value, func(match string) string {
+			if match == "$$" {
+				return "$"
+			}
+
+			groups := envPattern.FindStringSubmatch(match)
+			if value, found := os.LookupEnv(groups[1]); found && (value != "" || groups[2] == "") {
+				return value
+			}
+			if groups[2] != "" {
+				return groups[3]
+			}
+			missing = append(missing, groups[1])
+			return ""
+		})
+		if len(missing) > 0 {
+			return fmt.Errorf("%s:%d: environment variable %s is not set", path, node.Line, strings.Join(missing, ", "))
+		}
+
+		if value != node.Value {
+			node.Value = value
+			if node.Style == 0 {
+				node.Tag = "" // Let the expanded value decide between string, number or bool
+			}
+		}
+		return nil
+	}
+
+	for i, child := range node.Content {
+		// Mapping keys are never expanded
+		if node.Kind == yaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		if err := expandEnv(child, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveIncludes merges the fragments named by `include:` keys into their
+// mappings. Keys set locally win, and later includes win over earlier ones
+func (c *BotConfigs) resolveIncludes(node *yaml.Node, path string, including []string) error {
+	if node.Kind == yaml.MappingNode {
+		if value := mappingValue(node, includeKey); value != nil {
+			includes, err := includePaths(value, path)
+			if err != nil {
+				return err
+			}
+			removeKey(node, includeKey)
+
+			for i := len(includes) - 1; i >= 0; i-- {
+				fragment, err := c.loadDocument(includes[i], including)
+				if err != nil {
+					return err
+				}
+				if fragment.Kind == 0 {
+					continue
+				}
+				root := fragment.Content[0]
+				if root.Kind != yaml.MappingNode {
+					return fmt.Errorf("%s: included by %s:%d must be a mapping", includes[i], path, value.Line)
+				}
+				c.mergeMissing(node, root, includes[i])
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		if err := c.resolveIncludes(child, path, including); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// includePaths reads a single path or a list of paths, relative to path
+func includePaths(value *yaml.Node, path string) ([]string, error) {
+	var names []string
+	switch value.Kind {
+	case yaml.ScalarNode:
+		names = []string{value.Value}
+	case yaml.SequenceNode:
+		if err := value.Decode(&names); err != nil {
+			return nil, fmt.Errorf("%s:%d: include must list file paths", path, value.Line)
+		}
+	default:
+		return nil, fmt.Errorf("%s:%d: include must be a file path or a list of paths", path, value.Line)
+	}
+
+	paths := make([]string, len(names))
+	for i, name := range names {
+		if filepath.IsAbs(name) {
+			paths[i] = name
+		} else {
+			paths[i] = filepath.Join(filepath.Dir(path), name)
+		}
+	}
+	return paths, nil
+}
+
+// mergeMissing copies the keys of src, read from file, that dst lacks,
+// merging nested mappings. Copied values remember their file for errors
+func (c *BotConfigs) mergeMissing(dst, src *yaml.Node, file string) {
+	for k := 0; k+1 < len(src.Content); k += 2 {
+		key, value := src.Content[k], src.Content[k+1]
+
+		existing := mappingValue(dst, key.Value)
+		switch {
+		case existing == nil:
+			dst.Content = append(dst.Content, key, value)
+			if _, nested := c.included[value]; !nested {
+				c.included[value] = file
+			}
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			c.mergeMissing(existing, value, file)
+		}
+	}
+}
+
+// mappingValue returns the value stored under key, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for k := 0; k+1 < len(node.Content); k += 2 {
+		if node.Content[k].Value == key {
+			return node.Content[k+1]
+		}
+	}
+	return nil
+}
+
+func removeKey(node *yaml.Node, key string) {
+	for k := 0; k+1 < len(node.Content); k += 2 {
+		if node.Content[k].Value == key {
+			node.Content = append(node.Content[:k], node.Content[k+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigDir writes files, keyed by their relative path, into a temporary directory
+func writeConfigDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadBotConfigsFromADirectory(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"b.yml":             "bots:\n  - name: beta\n",
+		"a.yaml":            "bots:\n  - name: alpha\n",
+		"notes.txt":         "bots:\n  - name: ignored\n",
+		"shared/relay.yaml": "bots:\n  - name: fragment\n",
+	})
+
+	configs, err := LoadBotConfigs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, config := range configs.Bots {
+		names = append(names, config.Name)
+	}
+	if got := strings.Join(names, ","); got != "alpha,beta" {
+		t.Fatalf("bots = %s, want alpha,beta", got)
+	}
+}
+
+func TestLoadBotConfigsMergesIncludes(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"bots.yaml": `bots:
+  - name: alpha
+    include: [shared/relay.yaml, shared/dm.yaml]
+    listener: GroupListener
+`,
+		"shared/relay.yaml":   "relay_url: wss://first.example.com\nlistener: DMListener\n",
+		"shared/dm.yaml":      "relay_url: wss://second.example.com\ninclude: handler.yaml\n",
+		"shared/handler.yaml": "handler: DMHandler\n",
+	})
+
+	configs, err := LoadBotConfigs(filepath.Join(dir, "bots.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bot := configs.Bots[0]
+	if bot.Listener != "GroupListener" {
+		t.Errorf("listener = %q, want the local GroupListener", bot.Listener)
+	}
+	if bot.RelayURL != "wss://second.example.com" {
+		t.Errorf("relay_url = %q, want the later include to win", bot.RelayURL)
+	}
+	if bot.Handler != "DMHandler" {
+		t.Errorf("handler = %q, want DMHandler from the nested include", bot.Handler)
+	}
+}
+
+func TestLoadBotConfigsRejectsIncludeCycles(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"bots.yaml": "bots:\n  - name: alpha\n    include: a.yaml\n",
+		"a.yaml":    "include: b.yaml\n",
+		"b.yaml":    "include: a.yaml\n",
+	})
+
+	_, err := LoadBotConfigs(filepath.Join(dir, "bots.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Fatalf("load = %v, want an include cycle error", err)
+	}
+}
+
+func TestLoadBotConfigsExpandsEnv(t *testing.T) {
+	t.Setenv("AGENT_TEST_RELAY", "wss://relay.example.com")
+	t.Setenv("AGENT_TEST_EMPTY", "")
+
+	tests := []struct {
+		name  string
+		value string
+		want  string // Expanded value, or part of the error when failed is set
+		fail  bool
+	}{
+		{"set variable", "${AGENT_TEST_RELAY}", "wss://relay.example.com", false},
+		{"default of an unset variable", "${AGENT_TEST_UNSET:-fallback}", "fallback", false},
+		{"default of an empty variable", "${AGENT_TEST_EMPTY:-fallback}", "fallback", false},
+		{"empty variable without default", "x${AGENT_TEST_EMPTY}", "x", false},
+		{"escaped dollar", "price $$5", "price $5", false},
+		{"unset variable", "${AGENT_TEST_UNSET}", "bots.yaml:3: environment variable AGENT_TEST_UNSET is not set", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, "bots.yaml", "bots:\n  - name: alpha\n    relay_url: \""+tt.value+"\"\n")
+
+			configs, err := LoadBotConfigs(path)
+			if tt.fail {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("load = %v, want an error containing %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := configs.Bots[0].RelayURL; got != tt.want {
+				t.Fatalf("relay_url = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
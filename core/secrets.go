@@ -0,0 +1,112 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/nip49"
+	"golang.org/x/term"
+)
+
+// keySources lists the config fields a secret key can be read from
+var keySources = []string{"nsec", "nsec_env", "nsec_file", "ncryptsec"}
+
+// ResolveSecretKey returns the hex secret key of a bot from whichever source
+// its config names: an inline nsec, an environment variable, a file or a
+// NIP-49 encrypted key
+func ResolveSecretKey(config BotConfig) (string, error) {
+	switch sources := config.keySources(); len(sources) {
+	case 0:
+		return "", fmt.Errorf("one of %s is required", strings.Join(keySources, ", "))
+	case 1:
+	default:
+		return "", fmt.Errorf("only one key source may be set, got %s", strings.Join(sources, ", "))
+	}
+
+	switch {
+	case config.Nsec != "":
+		return DecodeSecretKey(config.Nsec)
+
+	case config.NsecEnv != "":
+		value, found := os.LookupEnv(config.NsecEnv)
+		if !found || value == "" {
+			return "", fmt.Errorf("environment variable %s is not set", config.NsecEnv)
+		}
+		return DecodeSecretKey(value)
+
+	case config.NsecFile != "":
+		data, err := os.ReadFile(config.NsecFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read key file: %w", err)
+		}
+		return DecodeSecretKey(string(data))
+
+	default:
+		passphrase, err := ncryptsecPassphrase(config)
+		if err != nil {
+			return "", err
+		}
+
+		sk, err := nip49.Decrypt(strings.TrimSpace(config.Ncryptsec), passphrase)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt ncryptsec: %w", err)
+		}
+		return sk, nil
+	}
+}
+
+// keySources returns the names of the key sources set in the config
+func (c BotConfig) keySources() []string {
+	values := []string{c.Nsec, c.NsecEnv, c.NsecFile, c.Ncryptsec}
+
+	var sources []string
+	for i, value := range values {
+		if value != "" {
+			sources = append(sources, keySources[i])
+		}
+	}
+	return sources
+}
+
+// DecodeSecretKey accepts a bech32 nsec or a hex secret key
+func DecodeSecretKey(value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	if nostr.IsValid32ByteHex(value) {
+		return value, nil
+	}
+
+	prefix, sk, err := nip19.Decode(value)
+	if err != nil || prefix != "nsec" {
+		return "", fmt.Errorf("must be a bech32 encoded nsec")
+	}
+	return sk.(string), nil
+}
+
+// ncryptsecPassphrase reads the passphrase from the configured environment
+// variable, or prompts for it when running in a terminal
+func ncryptsecPassphrase(config BotConfig) (string, error) {
+	if config.NcryptsecPassphraseEnv != "" {
+		passphrase, found := os.LookupEnv(config.NcryptsecPassphraseEnv)
+		if !found {
+			return "", fmt.Errorf("environment variable %s is not set", config.NcryptsecPassphraseEnv)
+		}
+		return passphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("ncryptsec_passphrase_env is required when not running in a terminal")
+	}
+
+	fmt.Fprintf(os.Stderr, "🔐 Passphrase for [%s]: ", config.Name)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
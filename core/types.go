@@ -7,11 +7,15 @@ type ContentStructure struct {
 }
 
 type BusMessage struct {
+	EventID           string           `json:"event_id,omitempty"` // Nostr event the message came from
 	ReceiverPublicKey string           `json:"receiver_pub_key,omitempty"`
 	SenderPublicKey   string           `json:"sender_pub_key,omitempty"`
-	ChannelID         string           `json:"channel_id,omitempty"` // For group/channel messages
-	Payload           ContentStructure `json:"content"`              // The message content
-	Timestamp         int64            `json:"timestamp"`            // When the message was created
+	ChannelID         string           `json:"channel_id,omitempty"`     // For group/channel messages
+	Payload           ContentStructure `json:"content"`                  // The message content
+	Timestamp         int64            `json:"timestamp"`                // When the message was created
+	CorrelationID     string           `json:"correlation_id,omitempty"` // Pairs a request with its reply
+	ReplyTo           EventType        `json:"reply_to,omitempty"`       // Where a reply to this message goes
+	Bridged           string           `json:"bridged,omitempty"`        // Bridge node the message arrived from, empty for local messages
 }
 
 // EventType defines a type for all supported event types
@@ -25,10 +29,13 @@ const (
 	GroupResponseEvent EventType = "group_response"
 	BotStartedEvent    EventType = "bot_started"
 	BotStoppedEvent    EventType = "bot_stopped"
+	DeadLetterEvent    EventType = "dead_letter" // Messages whose handler panicked
+	ReplyEvent         EventType = "reply"       // Replies to EventBus requests
 )
 
 type RemoteJob struct {
-	ChannelID string
-	SessionID string
-	Payload   string
+	ChannelID string `json:"channel_id"`
+	SessionID string `json:"session_id"`
+	Payload   string `json:"payload"`
+	Verb      string `json:"verb,omitempty"` // Worker command the job runs, empty for a crawl
 }
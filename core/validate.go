@@ -0,0 +1,524 @@
+package core
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/nip46"
+	"gopkg.in/yaml.v3"
+)
+
+// KnownComponents lists the names a config may refer to
+type KnownComponents struct {
+	Listeners  []string
+	Publishers []string
+	Handlers   []string
+	EventTypes []string
+	Programs   []string
+}
+
+// ValidationError describes a single problem found in a config file
+type ValidationError struct {
+	File    string
+	Line    int
+	Bot     string
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	location := e.File
+	if e.Line > 0 {
+		location = fmt.Sprintf("%s:%d", location, e.Line)
+	}
+	if e.Bot != "" {
+		return fmt.Sprintf("%s: [%s] %s: %s", location, e.Bot, e.Field, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, e.Field, e.Message)
+}
+
+// ValidationErrors collects every problem found in a config file
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateBotConfigs checks every bot against the config schema and reports
+// all problems at once. It returns nil when the configs are valid
+func ValidateBotConfigs(configs *BotConfigs, known KnownComponents) error {
+	v := &validator{configs: configs}
+
+	if len(configs.Bots) == 0 {
+		v.report(-1, "", "bots", "no bots defined", "bots")
+	}
+
+	names := make(map[string]bool)
+	for i, config := range configs.Bots {
+		if config.Name == "" {
+			v.report(i, "", "name", "is required")
+		} else if names[config.Name] {
+			v.report(i, config.Name, "name", "is used by more than one bot", "name")
+		}
+		names[config.Name] = true
+
+		v.validateBot(i, config, known)
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+type validator struct {
+	configs *BotConfigs
+	errs    ValidationErrors
+}
+
+// report records an error located at the YAML node for bots[index].path
+func (v *validator) report(index int, bot, field, message string, path ...interface{}) {
+	file, line := v.configs.locate(index, path...)
+
+	v.errs = append(v.errs, ValidationError{
+		File:    file,
+		Line:    line,
+		Bot:     bot,
+		Field:   field,
+		Message: message,
+	})
+}
+
+func (v *validator) validateBot(i int, config BotConfig, known KnownComponents) {
+	name := config.Name
+
+	// 🔑 Keys
+	secretKey := config.SecretKey
+	if config.keyErr != nil {
+		field, path := "nsec", []interface{}{}
+		if sources := config.keySources(); len(sources) > 0 {
+			field, path = strings.Join(sources, ", "), []interface{}{sources[0]}
+		}
+		v.report(i, name, field, config.keyErr.Error(), path...)
+	}
+
+	if config.Signer.IsRemote() {
+		if sources := config.keySources(); len(sources) > 0 {
+			v.report(i, name, "signer", fmt.Sprintf("cannot be combined with %s", strings.Join(sources, ", ")), "signer")
+		}
+		if config.Signer.Bunker != "" && !nip46.IsValidBunkerURL(config.Signer.Bunker) {
+			v.report(i, name, "signer.bunker", "must be a bunker:// URL with a hex public key and relays", "signer", "bunker")
+		}
+		if config.Signer.Timeout < 0 {
+			v.report(i, name, "signer.timeout", "must not be negative", "signer", "timeout")
+		}
+	}
+
+	if config.Npub != "" {
+		prefix, value, err := nip19.Decode(config.Npub)
+		if err != nil || prefix != "npub" {
+			v.report(i, name, "npub", "must be a bech32 encoded npub", "npub")
+		} else if secretKey != "" {
+			if pk, _ := nostr.GetPublicKey(secretKey); pk != value.(string) {
+				v.report(i, name, "npub", "does not match the public key of the secret key", "npub")
+			}
+		}
+	}
+
+	if config.ChannelID != "" && !nostr.IsValid32ByteHex(config.ChannelID) {
+		v.report(i, name, "channel_id", "must be a 64 character hex event id", "channel_id")
+	} else if config.ChannelID == "" && (config.Listener == "GroupListener" || config.Publisher == "GroupPublisher") {
+		v.report(i, name, "channel_id", "is required by GroupListener and GroupPublisher")
+	}
+
+	// 📡 Relays
+	if config.RelayURL != "" && !isURL(config.RelayURL, "ws", "wss") {
+		v.report(i, name, "relay_url", "must be a ws:// or wss:// URL", "relay_url")
+	}
+	for j, relay := range config.RelayURLs {
+		if !isURL(relay.URL, "ws", "wss") {
+			v.report(i, name, fmt.Sprintf("relay_urls[%d].url", j), "must be a ws:// or wss:// URL", "relay_urls", j, "url")
+		}
+	}
+
+	var readable, writable bool
+	for _, relay := range config.Relays() {
+		readable = readable || relay.CanRead()
+		writable = writable || relay.CanWrite()
+	}
+	switch {
+	case len(config.Relays()) == 0:
+		v.report(i, name, "relay_url", "one of relay_url or relay_urls is required")
+	case !readable:
+		v.report(i, name, "relay_urls", "at least one relay must be readable", "relay_urls")
+	case !writable:
+		v.report(i, name, "relay_urls", "at least one relay must be writable", "relay_urls")
+	}
+
+	if r := config.Reconnect; r.InitialDelay < 0 || r.MaxDelay < 0 || r.MaxAttempts < 0 {
+		v.report(i, name, "reconnect", "delays and max_attempts must not be negative", "reconnect")
+	} else if r.MaxDelay > 0 && r.InitialDelay > r.MaxDelay {
+		v.report(i, name, "reconnect.initial_delay", "must not exceed max_delay", "reconnect", "initial_delay")
+	}
+
+	if config.Cursor.MaxCatchUp < 0 {
+		v.report(i, name, "cursor.max_catch_up", "must not be negative", "cursor", "max_catch_up")
+	}
+
+	if config.MailboxSize < 0 {
+		v.report(i, name, "mailbox_size", "must not be negative", "mailbox_size")
+	}
+	if config.HistorySize < 0 {
+		v.report(i, name, "history_size", "must not be negative", "history_size")
+	}
+
+	if !contains([]string{"", "file", "bolt", "memory"}, config.State.Backend) {
+		v.report(i, name, "state.backend", "must be file, bolt or memory", "state", "backend")
+	}
+
+	bus := config.EventBus
+	if !contains([]string{"", "ordered", "unordered"}, bus.Mode) {
+		v.report(i, name, "event_bus.mode", "must be ordered or unordered", "event_bus", "mode")
+	}
+	if !contains([]string{"", "sender", "channel"}, bus.OrderBy) {
+		v.report(i, name, "event_bus.order_by", "must be sender or channel", "event_bus", "order_by")
+	}
+	if !contains([]string{"", "block", "drop"}, bus.Overflow) {
+		v.report(i, name, "event_bus.overflow", "must be block or drop", "event_bus", "overflow")
+	}
+	if bus.Workers < 0 || bus.QueueSize < 0 || bus.BlockTimeout < 0 {
+		v.report(i, name, "event_bus", "workers, queue_size and block_timeout must not be negative", "event_bus")
+	}
+
+	if bridge := config.Bridge; bridge.Enabled() {
+		if bridge.Listen != "" {
+			if _, _, err := ParseBridgeAddress(bridge.Listen); err != nil {
+				v.report(i, name, "bridge.listen", err.Error(), "bridge", "listen")
+			} else if ExposedBridgeAddress(bridge.Listen) && bridge.Secret == "" {
+				v.report(i, name, "bridge.secret", "is required to listen on a non-loopback TCP address", "bridge", "listen")
+			}
+		}
+		for j, address := range bridge.Connect {
+			if _, _, err := ParseBridgeAddress(address); err != nil {
+				v.report(i, name, fmt.Sprintf("bridge.connect[%d]", j), err.Error(), "bridge", "connect", j)
+			}
+		}
+		if len(bridge.Routes) == 0 {
+			v.report(i, name, "bridge.routes", "must list at least one event type to forward", "bridge")
+		}
+		for j, route := range bridge.Routes {
+			field := fmt.Sprintf("bridge.routes[%d]", j)
+			if route.Event == "" {
+				v.report(i, name, field+".event", "is required", "bridge", "routes", j)
+			}
+			if !contains([]string{"", "in", "out", "both"}, route.Direction) {
+				v.report(i, name, field+".direction", "must be in, out or both", "bridge", "routes", j, "direction")
+			}
+		}
+	}
+
+	if d := config.Dedup; d.Capacity < 0 || d.MaxPast < 0 || d.MaxFuture < 0 {
+		v.report(i, name, "dedup", "capacity, max_past and max_future must not be negative", "dedup")
+	} else if d.MaxPast > 0 && time.Duration(d.MaxPast)*time.Second < config.Cursor.MaxCatchUpDuration() {
+		v.report(i, name, "dedup.max_past", "must cover cursor.max_catch_up or replayed events are rejected", "dedup", "max_past")
+	}
+
+	// 🔌 Components
+	v.checkKnown(i, name, "listener", config.Listener, known.Listeners)
+	v.checkKnown(i, name, "publisher", config.Publisher, known.Publishers)
+	v.checkKnown(i, name, "handler", config.Handler, known.Handlers)
+	v.checkKnown(i, name, "event_type", config.EventType, known.EventTypes)
+
+	// 🧮 Programs
+	for j, program := range config.Programs {
+		v.validateProgram(i, name, program, known, "programs", j)
+	}
+	if config.ProgramConfig.Type != "" {
+		v.validateProgram(i, name, config.ProgramConfig, known, "program")
+	} else if !reflect.ValueOf(config.ProgramConfig).IsZero() {
+		v.report(i, name, "program.type", "program: block needs a type, migrate to programs:", "program")
+	}
+}
+
+func (v *validator) validateProgram(i int, name string, program ProgramConfig, known KnownComponents, path ...interface{}) {
+	field := func(key string) string {
+		if len(path) == 2 {
+			return fmt.Sprintf("%s[%d].%s", path[0], path[1], key)
+		}
+		return fmt.Sprintf("%s.%s", path[0], key)
+	}
+	at := func(keys ...interface{}) []interface{} {
+		return append(append([]interface{}{}, path...), keys...)
+	}
+
+	if program.Type == "" {
+		v.report(i, name, field("type"), "is required", path...)
+	} else if !contains(known.Programs, program.Type) {
+		v.report(i, name, field("type"), fmt.Sprintf("unknown program %q (known: %s)", program.Type, strings.Join(known.Programs, ", ")), at("type")...)
+	}
+
+	if program.MaxRunCount <= 0 {
+		v.report(i, name, field("max_run_count"), "must be a positive number", at("max_run_count")...)
+	}
+
+	if program.ResponseDelay < 0 {
+		v.report(i, name, field("response_delay"), "must not be negative", at("response_delay")...)
+	}
+
+	if program.Pattern != "" {
+		if _, err := regexp.Compile(program.Pattern); err != nil {
+			v.report(i, name, field("pattern"), fmt.Sprintf("does not compile: %v", err), at("pattern")...)
+		}
+	}
+
+	if program.Type == "ScheduledProgram" {
+		v.validateSchedule(i, name, program.Schedule, field, at)
+	}
+
+	if program.CallbackUrl != "" && !isURL(program.CallbackUrl, "http", "https") {
+		v.report(i, name, field("callback_url"), "must be an http:// or https:// URL", at("callback_url")...)
+	}
+
+	if program.HubConfig.Socket != "" && !isURL(program.HubConfig.Socket, "ws", "wss") {
+		v.report(i, name, field("hub.socket"), "must be a ws:// or wss:// URL", at("hub", "socket")...)
+	}
+
+	v.validateWorker(i, name, program.WorkerConfig, field, at, "worker")
+
+	if worker := program.WorkerConfig; worker.Concurrency < 0 || worker.QueueSize < 0 || worker.JobTimeout < 0 {
+		v.report(i, name, field("worker"), "concurrency, queue_size and job_timeout must not be negative", at("worker")...)
+	}
+
+	if len(program.Workers) > 0 {
+		v.validateWorkers(i, name, program.Workers, field, at)
+	}
+
+	for j, operator := range program.Operators {
+		if _, ok := HexPublicKey(operator); !ok {
+			v.report(i, name, field(fmt.Sprintf("operators[%d]", j)), "must be an npub or a hex public key", at("operators", j)...)
+		}
+	}
+}
+
+// validateWorker checks the connection settings of a worker found under prefix
+func (v *validator) validateWorker(i int, name string, worker WorkerConfig, field func(string) string, at func(...interface{}) []interface{}, prefix ...interface{}) {
+	key := func(keys ...interface{}) string {
+		parts := make([]string, 0, len(prefix)+len(keys))
+		for _, part := range append(append([]interface{}{}, prefix...), keys...) {
+			parts = append(parts, fmt.Sprint(part))
+		}
+		return field(strings.Join(parts, "."))
+	}
+	loc := func(keys ...interface{}) []interface{} {
+		return at(append(append([]interface{}{}, prefix...), keys...)...)
+	}
+
+	if worker.Url != "" && !isURL(worker.Url, "http", "https") {
+		v.report(i, name, key("url"), "must be an http:// or https:// URL", loc("url")...)
+	}
+
+	if worker.Address != "" {
+		if _, port, err := net.SplitHostPort(worker.Address); err != nil || port == "" {
+			v.report(i, name, key("address"), "must be a host:port address", loc("address")...)
+		}
+	}
+
+	if worker.Retry.MaxAttempts < 0 || worker.Retry.InitialDelay < 0 || worker.Retry.MaxDelay < 0 {
+		v.report(i, name, key("retry"), "max_attempts, initial_delay and max_delay must not be negative", loc("retry")...)
+	}
+
+	if worker.Breaker.Failures < 0 || worker.Breaker.Cooldown < 0 {
+		v.report(i, name, key("breaker"), "failures and cooldown must not be negative", loc("breaker")...)
+	}
+
+	if tls := worker.TLS; (tls.CertFile == "") != (tls.KeyFile == "") {
+		v.report(i, name, key("tls"), "cert_file and key_file must be set together", loc("tls")...)
+	}
+}
+
+// validateWorkers checks the command verbs of a ConductorProgram and their backends
+func (v *validator) validateWorkers(i int, name string, workers map[string]WorkerBackendConfig, field func(string) string, at func(...interface{}) []interface{}) {
+	verbs := make([]string, 0, len(workers))
+	for verb := range workers {
+		verbs = append(verbs, verb)
+	}
+	sort.Strings(verbs)
+
+	for _, verb := range verbs {
+		backend := workers[verb]
+		key := func(k string) string { return field("workers." + verb + "." + k) }
+		loc := func(keys ...interface{}) []interface{} { return at(append([]interface{}{"workers", verb}, keys...)...) }
+
+		if verb == "" || strings.ContainsAny(verb, " \t@") || contains(ReservedWorkerVerbs, verb) {
+			v.report(i, name, field("workers."+verb), fmt.Sprintf("must be a single word other than %s", strings.Join(ReservedWorkerVerbs, ", ")), loc()...)
+		}
+
+		switch backend.Kind {
+		case "crawler":
+			if backend.Address == "" {
+				v.report(i, name, key("address"), "is required for a crawler backend", loc()...)
+			}
+		case "http":
+			if backend.Url == "" {
+				v.report(i, name, key("url"), "is required for an http backend", loc()...)
+			}
+			if !contains([]string{"", "GET", "POST", "PUT"}, strings.ToUpper(backend.Method)) {
+				v.report(i, name, key("method"), "must be GET, POST or PUT", loc("method")...)
+			}
+		case "exec":
+			if len(backend.Command) == 0 {
+				v.report(i, name, key("command"), "is required for an exec backend", loc()...)
+			}
+			for _, part := range backend.Command {
+				if _, err := template.New("command").Parse(part); err != nil {
+					v.report(i, name, key("command"), fmt.Sprintf("invalid template: %v", err), loc("command")...)
+				}
+			}
+		default:
+			v.report(i, name, key("kind"), "must be crawler, http or exec", loc("kind")...)
+		}
+
+		if _, err := ParseArgSpecs(backend.Args); err != nil {
+			v.report(i, name, key("args"), err.Error(), loc("args")...)
+		}
+
+		if backend.Reply != "" {
+			if _, err := template.New("reply").Parse(backend.Reply); err != nil {
+				v.report(i, name, key("reply"), fmt.Sprintf("invalid template: %v", err), loc("reply")...)
+			}
+		}
+
+		if backend.Timeout < 0 {
+			v.report(i, name, key("timeout"), "must not be negative", loc("timeout")...)
+		}
+
+		if backend.Concurrency != 0 || backend.QueueSize != 0 || backend.JobTimeout != 0 {
+			v.report(i, name, field("workers."+verb), "concurrency, queue_size and job_timeout are shared, set them under worker", loc()...)
+		}
+
+		v.validateWorker(i, name, backend.WorkerConfig, field, at, "workers", verb)
+	}
+}
+
+func (v *validator) checkKnown(i int, name, field, value string, known []string) {
+	if value == "" {
+		v.report(i, name, field, "is required")
+	} else if !contains(known, value) {
+		v.report(i, name, field, fmt.Sprintf("unknown %s %q (known: %s)", field, value, strings.Join(known, ", ")), field)
+	}
+}
+
+// locate returns the file and line of the YAML node at the given path inside
+// bots[index], falling back to the closest parent that exists. Errors that
+// belong to no bot are reported against the config source
+func (c *BotConfigs) locate(index int, path ...interface{}) (string, int) {
+	if index < 0 || index >= len(c.origins) {
+		return c.source, 0
+	}
+
+	origin := c.origins[index]
+	file, node := origin.file, origin.node
+	if node == nil {
+		return file, 0
+	}
+
+	line := node.Line
+	for _, step := range path {
+		var next *yaml.Node
+
+		switch key := step.(type) {
+		case string:
+			if node.Kind == yaml.MappingNode {
+				for k := 0; k+1 < len(node.Content); k += 2 {
+					if node.Content[k].Value == key {
+						next = node.Content[k+1]
+						break
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && key < len(node.Content) {
+				next = node.Content[key]
+			}
+		}
+
+		if next == nil {
+			break
+		}
+		node = next
+		line = node.Line
+		if fragment, found := c.included[node]; found {
+			file = fragment
+		}
+	}
+	return file, line
+}
+
+func isURL(raw string, schemes ...string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return contains(schemes, u.Scheme)
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) validateSchedule(i int, name string, schedule ScheduleConfig, field func(string) string, at func(...interface{}) []interface{}) {
+	switch {
+	case schedule.Cron == "" && schedule.Every == 0:
+		v.report(i, name, field("schedule"), "needs cron or every", at("schedule")...)
+	case schedule.Cron != "" && schedule.Every != 0:
+		v.report(i, name, field("schedule"), "cron and every cannot be combined", at("schedule")...)
+	case schedule.Every < 0:
+		v.report(i, name, field("schedule.every"), "must be a positive number of seconds", at("schedule", "every")...)
+	case schedule.Cron != "":
+		if _, err := ParseCron(schedule.Cron); err != nil {
+			v.report(i, name, field("schedule.cron"), err.Error(), at("schedule", "cron")...)
+		}
+	}
+
+	if _, err := schedule.Location(); err != nil {
+		v.report(i, name, field("schedule.timezone"), fmt.Sprintf("unknown time zone: %v", err), at("schedule", "timezone")...)
+	}
+	if !contains([]string{"", "skip", "once", "all"}, schedule.CatchUp) {
+		v.report(i, name, field("schedule.catch_up"), "must be skip, once or all", at("schedule", "catch_up")...)
+	}
+
+	switch {
+	case schedule.Message == "" && schedule.Trigger == "":
+		v.report(i, name, field("schedule"), "needs a message to publish or a trigger for other programs", at("schedule")...)
+	case schedule.Message != "" && schedule.Trigger != "":
+		v.report(i, name, field("schedule"), "message and trigger cannot be combined", at("schedule")...)
+	case schedule.Message != "":
+		if _, err := template.New("message").Parse(schedule.Message); err != nil {
+			v.report(i, name, field("schedule.message"), fmt.Sprintf("invalid template: %v", err), at("schedule", "message")...)
+		}
+	}
+
+	if schedule.Receiver != "" && !nostr.IsValidPublicKey(schedule.Receiver) {
+		if prefix, _, err := nip19.Decode(schedule.Receiver); err != nil || prefix != "npub" {
+			v.report(i, name, field("schedule.receiver"), "must be an npub or a hex public key", at("schedule", "receiver")...)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+// writeConfig writes a config file into a temporary directory and returns its path
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testComponents lists the components the test configs refer to
+var testComponents = KnownComponents{
+	Listeners:  []string{"DMListener"},
+	Publishers: []string{"DMPublisher"},
+	Handlers:   []string{"DMHandler"},
+	EventTypes: []string{"dm"},
+	Programs:   []string{"ChatterProgram"},
+}
+
+func TestValidateReportsEveryErrorWithItsLine(t *testing.T) {
+	nsec, _ := nip19.EncodePrivateKey(nostr.GeneratePrivateKey())
+	path := writeConfig(t, "bots.yaml", `bots:
+  - name: alpha
+    nsec: `+nsec+`
+    relay_url: wss://relay.example.com
+    listener: DMListener
+    publisher: DMPublisher
+    handler: DMHandler
+    event_type: dm
+    programs:
+      - type: ChatterProgram
+        max_run_count: 3
+  - name: alpha
+    nsec: `+nsec+`
+    relay_url: https://relay.example.com
+    listener: RSSListener
+    publisher: DMPublisher
+    handler: DMHandler
+    event_type: dm
+    programs:
+      - type: MissingProgram
+        max_run_count: 0
+`)
+
+	configs, err := LoadBotConfigs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ValidateBotConfigs(configs, testComponents)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("validate = %v, want ValidationErrors", err)
+	}
+
+	want := []struct {
+		field string
+		line  int
+	}{
+		{"name", 12},
+		{"relay_url", 14},
+		{"listener", 15},
+		{"programs[0].type", 20},
+		{"programs[0].max_run_count", 21},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(want), errs)
+	}
+	for i, w := range want {
+		got := errs[i]
+		if got.Field != w.field || got.Line != w.line || got.Bot != "alpha" || got.File != path {
+			t.Errorf("error %d = %s:%d [%s] %s, want %s:%d [alpha] %s", i, got.File, got.Line, got.Bot, got.Field, path, w.line, w.field)
+		}
+	}
+}
+
+func TestValidateAcceptsAValidConfig(t *testing.T) {
+	nsec, _ := nip19.EncodePrivateKey(nostr.GeneratePrivateKey())
+	path := writeConfig(t, "bots.yaml", `bots:
+  - name: alpha
+    nsec: `+nsec+`
+    relay_url: wss://relay.example.com
+    listener: DMListener
+    publisher: DMPublisher
+    handler: DMHandler
+    event_type: dm
+    programs:
+      - type: ChatterProgram
+        max_run_count: 3
+`)
+
+	configs, err := LoadBotConfigs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateBotConfigs(configs, testComponents); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+}
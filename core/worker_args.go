@@ -0,0 +1,89 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var argName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ArgSpec is one named argument of a worker command
+type ArgSpec struct {
+	Name     string
+	Optional bool // Written "name?", may be left out
+	Rest     bool // Written "name...", takes the rest of the message
+}
+
+// ParseArgSpecs reads an argument schema such as ["city", "days?"] or
+// ["query..."]. Optional arguments follow the required ones, and only the
+// last argument may take the rest of the message
+func ParseArgSpecs(specs []string) ([]ArgSpec, error) {
+	parsed := make([]ArgSpec, 0, len(specs))
+	seen := make(map[string]bool)
+
+	for i, spec := range specs {
+		arg := ArgSpec{Name: spec}
+		if name, found := strings.CutSuffix(spec, "..."); found {
+			arg.Name, arg.Rest = name, true
+		}
+		if name, found := strings.CutSuffix(arg.Name, "?"); found {
+			arg.Name, arg.Optional = name, true
+		}
+
+		switch {
+		case !argName.MatchString(arg.Name):
+			return nil, fmt.Errorf("argument %q must be a lowercase name, optionally ending in ? or ...", spec)
+		case seen[arg.Name]:
+			return nil, fmt.Errorf("argument %q is repeated", arg.Name)
+		case arg.Rest && i != len(specs)-1:
+			return nil, fmt.Errorf("only the last argument may end in ..., not %q", spec)
+		case !arg.Optional && i > 0 && parsed[i-1].Optional:
+			return nil, fmt.Errorf("required argument %q follows an optional one", arg.Name)
+		}
+
+		seen[arg.Name] = true
+		parsed = append(parsed, arg)
+	}
+	return parsed, nil
+}
+
+// BindArgs matches the words after a command verb to the schema
+func BindArgs(specs []ArgSpec, words []string) (map[string]string, error) {
+	args := make(map[string]string, len(specs))
+
+	for i, spec := range specs {
+		switch {
+		case i >= len(words):
+			if !spec.Optional {
+				return nil, fmt.Errorf("missing %s", spec.Name)
+			}
+		case spec.Rest:
+			args[spec.Name] = strings.Join(words[i:], " ")
+		default:
+			args[spec.Name] = words[i]
+		}
+	}
+
+	if len(words) > len(specs) && (len(specs) == 0 || !specs[len(specs)-1].Rest) {
+		return nil, fmt.Errorf("unexpected %q", strings.Join(words[len(specs):], " "))
+	}
+	return args, nil
+}
+
+// ArgUsage renders the schema for help replies, e.g. "<city> [days]"
+func ArgUsage(specs []ArgSpec) string {
+	parts := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		name := spec.Name
+		if spec.Rest {
+			name += "..."
+		}
+		if spec.Optional {
+			parts = append(parts, "["+name+"]")
+		} else {
+			parts = append(parts, "<"+name+">")
+		}
+	}
+	return strings.Join(parts, " ")
+}
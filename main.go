@@ -2,19 +2,34 @@ package main
 
 import (
 	"agent/bot"
-	"agent/bot/handlers"
-	"agent/bot/listeners"
-	"agent/bot/publishers"
 	"agent/core"
+	"context"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	// Components register themselves with the bot registry
+	_ "agent/bot/handlers"
+	_ "agent/bot/listeners"
+	_ "agent/bot/publishers"
 )
 
 func main() {
 	initializeLogging()
 
+	// Run a subcommand when one is given
+	if len(os.Args) > 1 && runCommand(os.Args[1], os.Args[2:]) {
+		return
+	}
+
 	// Parse command-line flags
-	configFile := flag.String("config", "", "Path to YAML configuration file for the bot")
+	configFile := flag.String("config", "", "YAML configuration file, directory of files or glob for the bots")
+	adminAddr := flag.String("admin", "", "Address of the admin HTTP server, e.g. 127.0.0.1:8081 (disabled when empty)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 8*time.Second, "How long in-flight work may take to finish on shutdown")
 	flag.Parse()
 
 	if *configFile == "" {
@@ -27,121 +42,89 @@ func main() {
 		log.Fatalf("❌ Could not load bot configuration: %v", err)
 	}
 
+	// Report every configuration problem before starting anything
+	if err := core.ValidateBotConfigs(botConfigs, knownComponents()); err != nil {
+		log.Fatalf("❌ Invalid bot configuration:\n%v", err)
+	}
+
 	// Initialize the shared BotManager
 	manager := bot.NewBotManager()
 
-	// Dynamically start bots based on YAML configuration
+	// Dynamically create bots based on YAML configuration
 	for _, botCfg := range botConfigs.Bots {
-		startDynamicBot(botCfg, manager)
+		if _, err := manager.CreateBot(botCfg); err != nil {
+			log.Fatalf("❌ Could not start bot %s: %v", botCfg.Name, err)
+		}
 	}
 
 	// Start all bots concurrently
 	manager.StartAll()
 
-	// Keep the program running
-	select {}
-}
-
-// 🔄 Set up logging format
-func initializeLogging() {
-	log.SetPrefix("[agent] ")
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
-}
-
-// 🚀 Dynamically initialize and start a bot based on config
-func startDynamicBot(config core.BotConfig, manager *bot.BotManager) {
-	log.Printf("🤖 Starting bot: %s...", config.Name)
-
-	eventBus := bot.NewEventBus()
-	if eventBus == nil {
-		log.Fatalf("❌ Failed to initialize EventBus for %s", config.Name)
+	reload := func() (bot.ReloadReport, error) {
+		return reloadConfig(*configFile, manager, *shutdownTimeout)
 	}
 
-	listener := initializeListener(config.Listener, config.ChannelID)
-	publisher := initializePublisher(config.Publisher, config.ChannelID)
-
-	// Initialize the bot
-	bot := bot.NewBaseBot(
-		config,
-		listener,
-		publisher,
-		eventBus,
-	)
-
-	handler := initializeHandler(
-		config.Handler,
-		config.ChannelID,
-		manager,
-		bot,
-	)
-
-	manager.AddBot(bot)
+	if *adminAddr != "" {
+		go serveAdmin(*adminAddr, manager, reload)
+	}
 
-	handler.Subscribe(eventBus)
+	// Keep the program running until Ctrl-C or `docker stop`, reloading on SIGHUP
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-	eventBus.Subscribe(getEventType(config.EventType), func(message *core.BusMessage) {
-		if err := publisher.Broadcast(bot, message); err != nil {
-			log.Printf("❌ [%s] Failed to broadcast message: %v", config.Name, err)
+	var received os.Signal
+	for received = range signals {
+		if received != syscall.SIGHUP {
+			break
 		}
-	})
-}
+		log.Println("🔄 Received SIGHUP, reloading configuration...")
+		go func() {
+			if _, err := reload(); err != nil {
+				log.Printf("❌ Reload failed: %v", err)
+			}
+		}()
+	}
+	log.Printf("🛑 Received %s, shutting down (send again to force)...", received)
+
+	go func() {
+		for received := range signals {
+			if received != syscall.SIGHUP {
+				log.Println("💀 Forced exit")
+				os.Exit(1)
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
 
-//////////////////////////////////////////////////////////////////////////////////////
-// ✅ Dynamic Resolver Functions
-//////////////////////////////////////////////////////////////////////////////////////
-
-func initializeListener(listenerType, channelID string) bot.EventListener {
-	switch listenerType {
-	case "DMListener":
-		return &listeners.DMListener{}
-	case "GroupListener":
-		return &listeners.GroupListener{ChannelID: channelID}
-	default:
-		log.Fatalf("❌ Unknown listener type: %s", listenerType)
-		return nil
+	// A failed drain exits non-zero so process supervisors notice it
+	if err := manager.StopAll(ctx); err != nil {
+		log.Fatalf("⚠️ Shutdown incomplete: %v", err)
 	}
+	log.Println("👋 All bots stopped")
 }
 
-func initializePublisher(publisherType, channelID string) bot.Publisher {
-	switch publisherType {
-	case "DMPublisher":
-		return &publishers.DMPublisher{}
-	case "GroupPublisher":
-		return &publishers.GroupPublisher{ChannelID: channelID}
-	default:
-		log.Fatalf("❌ Unknown publisher type: %s", publisherType)
-		return nil
-	}
+// 🔄 Set up logging format
+func initializeLogging() {
+	log.SetPrefix("[agent] ")
+	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
 }
 
-func initializeHandler(handlerType, channelID string, manager *bot.BotManager, botInstance *bot.BaseBot) bot.EventHandler {
-	switch handlerType {
-	case "ExchangeHandler":
-		return &handlers.ExchangeHandler{
-			ChannelID: channelID,
-			Manager:   manager,
-			Bot:       botInstance,
-		}
-	case "SupportHandler":
-		return &handlers.SupportHandler{}
-	case "GroupHandler":
-		return &handlers.GroupHandler{ChannelID: channelID}
-	case "WelcomeHandler":
-		return &handlers.WelcomeHandler{ChannelID: channelID}
-	default:
-		log.Fatalf("❌ Unknown handler type: %s", handlerType)
-		return nil
+// 🔄 Reload the config file and apply it to the running bots
+func reloadConfig(path string, manager *bot.BotManager, timeout time.Duration) (bot.ReloadReport, error) {
+	botConfigs, err := core.LoadBotConfigs(path)
+	if err != nil {
+		return bot.ReloadReport{}, err
 	}
-}
 
-func getEventType(eventType string) core.EventType {
-	switch eventType {
-	case "DMResponseEvent":
-		return core.DMResponseEvent
-	case "GroupResponseEvent":
-		return core.GroupResponseEvent
-	default:
-		log.Fatalf("❌ Unknown event type: %s", eventType)
-		return ""
+	// Keep the running bots when the new file is broken
+	if err := core.ValidateBotConfigs(botConfigs, knownComponents()); err != nil {
+		return bot.ReloadReport{}, fmt.Errorf("invalid bot configuration, keeping the running bots:\n%w", err)
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return manager.Reload(ctx, botConfigs.Bots)
 }
@@ -0,0 +1,119 @@
+package grpcclient
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState tells whether calls are let through
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // Calls go through
+	BreakerOpen     BreakerState = "open"      // Calls fail at once until the cooldown passes
+	BreakerHalfOpen BreakerState = "half_open" // One probe call decides whether to close again
+)
+
+// Breaker stops calling a service that keeps failing, so callers fail fast
+// instead of waiting on retries, and lets a single call through after a
+// cooldown to find out whether it recovered
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool // A half-open probe call is in flight
+	state     BreakerState
+}
+
+// NewBreaker opens after threshold consecutive failures and stays open for cooldown
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown, state: BreakerClosed}
+}
+
+// Allow reports whether a call may go ahead. Every allowed call must be
+// followed by Record or Forget
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.current() {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// Record reports how an allowed call went
+func (b *Breaker) Record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if ok {
+		b.failures = 0
+		b.state = BreakerClosed
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// Forget releases an allowed call that ended without telling anything about
+// the service, e.g. because the caller gave up
+func (b *Breaker) Forget() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// Ready reports whether Allow would let a call through right now, without
+// claiming the half-open probe
+func (b *Breaker) Ready() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.current() {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		return !b.probing
+	}
+	return true
+}
+
+// State returns the breaker's state
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current()
+}
+
+// RetryIn returns how long until an open breaker lets a probe through
+func (b *Breaker) RetryIn() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.current() != BreakerOpen {
+		return 0
+	}
+	return time.Until(b.openedAt.Add(b.cooldown)).Round(time.Second)
+}
+
+// current moves an open breaker to half-open once the cooldown passed. Callers hold b.mu
+func (b *Breaker) current() BreakerState {
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		b.state = BreakerHalfOpen
+	}
+	return b.state
+}
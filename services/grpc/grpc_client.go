@@ -1,74 +1,202 @@
 package grpcclient
 
 import (
+	"agent/core"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"sync"
 	"time"
 
 	pb "github.com/prorobot-ai/grpc-protos/gen/crawler"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// ErrCrawlerUnavailable is returned when the crawler cannot be reached, either
+// after retrying or at once while the circuit breaker is open
+var ErrCrawlerUnavailable = errors.New("crawler unavailable")
+
+// CrawlerClient talks to the crawler service. The connection is made lazily,
+// so creating a client never blocks. Calls are retried with backoff and go
+// through a circuit breaker, and the crawler's health is probed in the background
 type CrawlerClient struct {
-	conn   *grpc.ClientConn
-	client pb.CrawlerServiceClient
+	address string
+	conn    *grpc.ClientConn
+	client  pb.CrawlerServiceClient
+
+	attempts     int
+	initialDelay time.Duration
+	maxDelay     time.Duration
+	breaker      *Breaker
+
+	mu     sync.Mutex
+	health Health
+	stop   context.CancelFunc
+	done   chan struct{}
+}
+
+// ✅ Initialize the gRPC Client from the worker config
+func NewCrawlerClient(config core.WorkerConfig) (*CrawlerClient, error) {
+	return newCrawlerClient(config)
 }
 
-// ✅ Initialize the gRPC Client
-func NewCrawlerClient(serverAddr string) (*CrawlerClient, error) {
-	conn, err := grpc.Dial(serverAddr, grpc.WithInsecure(), grpc.WithBlock())
+// newCrawlerClient takes extra dial options, e.g. an in-memory dialer in tests
+func newCrawlerClient(config core.WorkerConfig, options ...grpc.DialOption) (*CrawlerClient, error) {
+	creds, err := transportCredentials(config.TLS)
 	if err != nil {
 		return nil, err
 	}
 
-	client := pb.NewCrawlerServiceClient(conn)
-	return &CrawlerClient{conn: conn, client: client}, nil
+	options = append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, options...)
+	conn, err := grpc.NewClient(config.Address, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to crawler service: %w", err)
+	}
+
+	initial, max := config.Retry.Delays()
+	c := &CrawlerClient{
+		address:      config.Address,
+		conn:         conn,
+		client:       pb.NewCrawlerServiceClient(conn),
+		attempts:     config.Retry.Attempts(),
+		initialDelay: initial,
+		maxDelay:     max,
+		breaker:      NewBreaker(config.Breaker.Threshold(), config.Breaker.CooldownDuration()),
+		health:       Health{Address: config.Address, Status: "unknown"},
+		done:         make(chan struct{}),
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	c.stop = stop
+	go func() {
+		defer close(c.done)
+		if every := config.HealthEvery(); every > 0 {
+			c.probe(ctx, every)
+		}
+	}()
+
+	return c, nil
 }
 
-// ✅ Send a Crawl Request
-func (c *CrawlerClient) StartCrawl(url, jobID string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// ✅ Send a Crawl Request, calling progress for every update until the crawl
+// ends or ctx does. Only a crawl that has not reported progress yet is retried
+func (c *CrawlerClient) StartCrawl(ctx context.Context, url, jobID string, progress func(*pb.CrawlResponse)) error {
+	return c.call(ctx, "StartCrawl", func(ctx context.Context) (bool, error) {
+		stream, err := c.client.StartCrawl(ctx, &pb.CrawlRequest{
+			Url:   url,
+			JobId: jobID,
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to start crawl: %w", err)
+		}
 
-	stream, err := c.client.StartCrawl(ctx, &pb.CrawlRequest{
-		Url:   url,
-		JobId: jobID,
+		started := false
+		err = receive(stream, func(resp *pb.CrawlResponse) {
+			started = true
+			progress(resp)
+		})
+		return started, err
 	})
-	if err != nil {
-		log.Fatalf("❌ Failed to start crawl: %v", err)
-	}
+}
 
-	// Read streaming response
-	for {
-		resp, err := stream.Recv()
+// ✅ Fetch Job Status, calling update for every status the crawler reports
+func (c *CrawlerClient) GetJobStatus(ctx context.Context, jobID string, update func(*pb.JobStatusResponse)) error {
+	return c.call(ctx, "GetJobStatus", func(ctx context.Context) (bool, error) {
+		stream, err := c.client.GetJobStatus(ctx, &pb.JobStatusRequest{JobId: jobID})
 		if err != nil {
-			break
+			return false, fmt.Errorf("failed to get job status: %w", err)
 		}
-		log.Printf("🔄 Crawl Progress: %s", resp.Message)
-	}
+
+		started := false
+		err = receive(stream, func(resp *pb.JobStatusResponse) {
+			started = true
+			update(resp)
+		})
+		return started, err
+	})
 }
 
-// ✅ Fetch Job Status
-func (c *CrawlerClient) GetJobStatus(jobID string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// ✅ Available reports whether the circuit breaker would let a call through.
+// It is false while a half-open probe is in flight, since other calls would
+// fail at once
+func (c *CrawlerClient) Available() bool {
+	return c.breaker.Ready()
+}
 
-	stream, err := c.client.GetJobStatus(ctx, &pb.JobStatusRequest{JobId: jobID})
-	if err != nil {
-		log.Fatalf("❌ Failed to get job status: %v", err)
+// ✅ Close stops health probing and the gRPC connection
+func (c *CrawlerClient) Close() error {
+	c.stop()
+	<-c.done
+	return c.conn.Close()
+}
+
+// call runs attempt through the breaker, retrying transient failures with a
+// doubling delay. attempt reports whether the call got far enough that
+// repeating it would repeat its effects, in which case it is not retried
+func (c *CrawlerClient) call(ctx context.Context, method string, attempt func(context.Context) (bool, error)) error {
+	if !c.breaker.Allow() {
+		return fmt.Errorf("%w: circuit open, next try in %s", ErrCrawlerUnavailable, c.breaker.RetryIn())
+	}
+
+	delay := c.initialDelay
+	for try := 1; ; try++ {
+		started, err := attempt(ctx)
+		switch {
+		case err == nil:
+			c.breaker.Record(true)
+			return nil
+		case ctx.Err() != nil:
+			c.breaker.Forget()
+			return ctx.Err()
+		case !transient(err):
+			// The crawler answered, it just refused this call
+			c.breaker.Record(true)
+			return err
+		case started || try >= c.attempts:
+			c.breaker.Record(false)
+			return fmt.Errorf("%w: %v", ErrCrawlerUnavailable, err)
+		}
+
+		log.Printf("🔁 Crawler %s failed (try %d/%d), retrying in %s: %v", method, try, c.attempts, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			c.breaker.Forget()
+			return ctx.Err()
+		}
+		delay = min(delay*2, c.maxDelay)
 	}
+}
+
+// transient tells whether a failure is worth retrying
+func transient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
 
-	// Read streaming response
+type serverStream[T any] interface {
+	Recv() (*T, error)
+}
+
+// receive reads a server stream until it ends. A clean end of stream is not an error
+func receive[T any](stream serverStream[T], fn func(*T)) error {
 	for {
 		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
 		if err != nil {
-			break
+			return err
 		}
-		log.Printf("📡 Job [%s] Status: %s", resp.JobId, resp.Status)
+		fn(resp)
 	}
 }
-
-// ✅ Close the gRPC connection
-func (c *CrawlerClient) Close() {
-	c.conn.Close()
-}
@@ -0,0 +1,297 @@
+package grpcclient
+
+import (
+	"agent/core"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/prorobot-ai/grpc-protos/gen/crawler"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeCrawler answers StartCrawl with whatever crawl does for the nth call
+type fakeCrawler struct {
+	pb.UnimplementedCrawlerServiceServer
+
+	mu    sync.Mutex
+	calls int
+	crawl func(call int, stream pb.CrawlerService_StartCrawlServer) error
+}
+
+func (f *fakeCrawler) StartCrawl(req *pb.CrawlRequest, stream pb.CrawlerService_StartCrawlServer) error {
+	f.mu.Lock()
+	f.calls++
+	call := f.calls
+	f.mu.Unlock()
+	return f.crawl(call, stream)
+}
+
+func (f *fakeCrawler) GetJobStatus(req *pb.JobStatusRequest, stream pb.CrawlerService_GetJobStatusServer) error {
+	for _, state := range []string{"running", "done"} {
+		if err := stream.Send(&pb.JobStatusResponse{JobId: req.JobId, Status: state}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeCrawler) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// crawlerServer is an in-process crawler with a health service
+type crawlerServer struct {
+	listener *bufconn.Listener
+	server   *grpc.Server
+	health   *health.Server
+	crawler  *fakeCrawler
+}
+
+func newCrawlerServer(t *testing.T, crawl func(int, pb.CrawlerService_StartCrawlServer) error) *crawlerServer {
+	t.Helper()
+
+	s := &crawlerServer{
+		listener: bufconn.Listen(1 << 20),
+		server:   grpc.NewServer(),
+		health:   health.NewServer(),
+		crawler:  &fakeCrawler{crawl: crawl},
+	}
+	pb.RegisterCrawlerServiceServer(s.server, s.crawler)
+	healthpb.RegisterHealthServer(s.server, s.health)
+	go s.server.Serve(s.listener)
+	t.Cleanup(s.server.Stop)
+	return s
+}
+
+// client connects to the server with millisecond retry delays and the given breaker
+func (s *crawlerServer) client(t *testing.T, attempts int, breaker *Breaker) *CrawlerClient {
+	t.Helper()
+
+	config := core.WorkerConfig{
+		Address:        "passthrough:///bufnet",
+		Retry:          core.RetryConfig{MaxAttempts: attempts},
+		HealthInterval: -1,
+	}
+	client, err := newCrawlerClient(config, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return s.listener.DialContext(ctx)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.initialDelay, client.maxDelay = time.Millisecond, 4*time.Millisecond
+	if breaker != nil {
+		client.breaker = breaker
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func unavailable(int, pb.CrawlerService_StartCrawlServer) error {
+	return status.Error(codes.Unavailable, "crawler restarting")
+}
+
+func crawl(client *CrawlerClient) ([]string, error) {
+	var messages []string
+	err := client.StartCrawl(context.Background(), "https://example.com", "1", func(resp *pb.CrawlResponse) {
+		messages = append(messages, resp.Message)
+	})
+	return messages, err
+}
+
+func TestStartCrawlRetriesUnavailable(t *testing.T) {
+	server := newCrawlerServer(t, func(call int, stream pb.CrawlerService_StartCrawlServer) error {
+		if call < 3 {
+			return status.Error(codes.Unavailable, "crawler restarting")
+		}
+		stream.Send(&pb.CrawlResponse{JobId: "w1", Message: "50%"})
+		return stream.Send(&pb.CrawlResponse{JobId: "w1", Message: "done"})
+	})
+	client := server.client(t, 3, nil)
+
+	messages, err := crawl(client)
+	if err != nil {
+		t.Fatalf("crawl: %v", err)
+	}
+	if len(messages) != 2 || messages[1] != "done" {
+		t.Fatalf("progress = %v, want 50%% and done", messages)
+	}
+	if calls := server.crawler.Calls(); calls != 3 {
+		t.Fatalf("crawler called %d times, want 3", calls)
+	}
+}
+
+func TestStartCrawlGivesUpAfterMaxAttempts(t *testing.T) {
+	server := newCrawlerServer(t, unavailable)
+	client := server.client(t, 3, nil)
+
+	if _, err := crawl(client); !errors.Is(err, ErrCrawlerUnavailable) {
+		t.Fatalf("crawl = %v, want ErrCrawlerUnavailable", err)
+	}
+	if calls := server.crawler.Calls(); calls != 3 {
+		t.Fatalf("crawler called %d times, want 3", calls)
+	}
+}
+
+func TestStartCrawlNotRetriedAfterProgress(t *testing.T) {
+	server := newCrawlerServer(t, func(call int, stream pb.CrawlerService_StartCrawlServer) error {
+		stream.Send(&pb.CrawlResponse{JobId: "w1", Message: "10%"})
+		return status.Error(codes.Unavailable, "crawler restarting")
+	})
+	client := server.client(t, 3, nil)
+
+	messages, err := crawl(client)
+	if !errors.Is(err, ErrCrawlerUnavailable) {
+		t.Fatalf("crawl = %v, want ErrCrawlerUnavailable", err)
+	}
+	if calls := server.crawler.Calls(); calls != 1 || len(messages) != 1 {
+		t.Fatalf("crawler called %d times with %d updates, want 1 and 1", calls, len(messages))
+	}
+}
+
+func TestStartCrawlRefusalIsNotRetried(t *testing.T) {
+	server := newCrawlerServer(t, func(int, pb.CrawlerService_StartCrawlServer) error {
+		return status.Error(codes.InvalidArgument, "bad url")
+	})
+	client := server.client(t, 3, NewBreaker(1, time.Hour))
+
+	if _, err := crawl(client); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("crawl = %v, want InvalidArgument", err)
+	}
+	if calls := server.crawler.Calls(); calls != 1 {
+		t.Fatalf("crawler called %d times, want 1", calls)
+	}
+	if !client.Available() {
+		t.Fatal("a refused call opened the breaker")
+	}
+}
+
+func TestGetJobStatusStreams(t *testing.T) {
+	client := newCrawlerServer(t, unavailable).client(t, 1, nil)
+
+	var states []string
+	err := client.GetJobStatus(context.Background(), "w1", func(resp *pb.JobStatusResponse) {
+		states = append(states, resp.Status)
+	})
+	if err != nil || len(states) != 2 || states[1] != "done" {
+		t.Fatalf("status = %v, %v", states, err)
+	}
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		healthy bool
+	)
+	probing := make(chan struct{})
+	release := make(chan struct{})
+	server := newCrawlerServer(t, func(call int, stream pb.CrawlerService_StartCrawlServer) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !healthy {
+			return status.Error(codes.Unavailable, "down")
+		}
+		close(probing)
+		<-release
+		return stream.Send(&pb.CrawlResponse{Message: "done"})
+	})
+	client := server.client(t, 1, NewBreaker(2, 50*time.Millisecond))
+
+	// Two failures in a row open the breaker
+	crawl(client)
+	crawl(client)
+	if state := client.breaker.State(); state != BreakerOpen || client.Available() {
+		t.Fatalf("breaker %s, available %v after 2 failures", state, client.Available())
+	}
+	if _, err := crawl(client); !errors.Is(err, ErrCrawlerUnavailable) || server.crawler.Calls() != 2 {
+		t.Fatalf("open breaker let a call through: %v", err)
+	}
+
+	// After the cooldown one probe is let through, and nothing else while it runs
+	time.Sleep(60 * time.Millisecond)
+	if state := client.breaker.State(); state != BreakerHalfOpen || !client.Available() {
+		t.Fatalf("breaker %s, available %v after the cooldown", state, client.Available())
+	}
+	mu.Lock()
+	healthy = true
+	mu.Unlock()
+	probed := make(chan error, 1)
+	go func() {
+		_, err := crawl(client)
+		probed <- err
+	}()
+	<-probing
+	if client.Available() {
+		t.Fatal("available while the half-open probe is in flight")
+	}
+	if _, err := crawl(client); !errors.Is(err, ErrCrawlerUnavailable) {
+		t.Fatalf("second call during the probe = %v, want ErrCrawlerUnavailable", err)
+	}
+
+	// A successful probe closes it
+	close(release)
+	if err := <-probed; err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if state := client.breaker.State(); state != BreakerClosed || !client.Available() {
+		t.Fatalf("breaker %s, available %v after a good probe", state, client.Available())
+	}
+}
+
+func TestBreakerReopensOnFailedProbe(t *testing.T) {
+	client := newCrawlerServer(t, unavailable).client(t, 1, NewBreaker(1, 30*time.Millisecond))
+
+	crawl(client)
+	time.Sleep(40 * time.Millisecond)
+	if state := client.breaker.State(); state != BreakerHalfOpen {
+		t.Fatalf("breaker %s after the cooldown, want half_open", state)
+	}
+	crawl(client)
+	if state := client.breaker.State(); state != BreakerOpen {
+		t.Fatalf("breaker %s after a failed probe, want open", state)
+	}
+}
+
+func TestHealthProbing(t *testing.T) {
+	server := newCrawlerServer(t, unavailable)
+	client := server.client(t, 1, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.probe(ctx, 10*time.Millisecond)
+
+	eventually := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for client.Health().Status != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("health = %+v, want %s", client.Health(), want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	eventually("serving")
+	if health := client.Health(); health.Breaker != BreakerClosed || health.CheckedAt.IsZero() {
+		t.Fatalf("health = %+v, want a checked, closed breaker", health)
+	}
+
+	server.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	eventually("not_serving")
+
+	server.server.Stop()
+	eventually("unreachable")
+	if client.Health().Error == "" {
+		t.Fatal("unreachable crawler reported no error")
+	}
+}
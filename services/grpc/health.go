@@ -0,0 +1,77 @@
+package grpcclient
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const healthCheckTimeout = 5 * time.Second
+
+// Health is what the client last learned about the crawler
+type Health struct {
+	Address   string       `json:"address"`
+	Status    string       `json:"status"` // serving, not_serving, unreachable, or unknown before the first probe or without a health service
+	Breaker   BreakerState `json:"breaker"`
+	Error     string       `json:"error,omitempty"`
+	CheckedAt time.Time    `json:"checked_at,omitempty"`
+}
+
+// Health returns the last probe result and the breaker state
+func (c *CrawlerClient) Health() Health {
+	c.mu.Lock()
+	health := c.health
+	c.mu.Unlock()
+
+	health.Breaker = c.breaker.State()
+	return health
+}
+
+// probe checks the crawler through the standard gRPC health service until ctx ends
+func (c *CrawlerClient) probe(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		c.check(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *CrawlerClient) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	health := Health{Address: c.address, CheckedAt: time.Now()}
+	resp, err := healthpb.NewHealthClient(c.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	switch {
+	case err == nil:
+		health.Status = strings.ToLower(resp.GetStatus().String())
+	case status.Code(err) == codes.Unimplemented:
+		health.Status = "unknown"
+	default:
+		if ctx.Err() != nil && ctx.Err() != context.DeadlineExceeded {
+			return
+		}
+		health.Status, health.Error = "unreachable", err.Error()
+	}
+
+	c.mu.Lock()
+	changed := c.health.Status != health.Status
+	c.health = health
+	c.mu.Unlock()
+
+	if changed {
+		log.Printf("🩺 Crawler [%s] is %s", c.address, health.Status)
+	}
+}